@@ -0,0 +1,429 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/arduino/arduino-cli/arduino/globals"
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/go-paths-helper"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Builder prepares the source files of a sketch in the build path.
+// The zero value of each option keeps the default behavior.
+type Builder struct {
+	sketch *sketch.Sketch
+	// sketchMux guards the replacement of the sketch, see SetSketch
+	sketchMux sync.RWMutex
+
+	// OutputBaseName is the base name, without extension, of the merged .cpp
+	// file saved in the build path. If empty the name of the sketch main file
+	// is used (for example "Blink.ino" produces "Blink.ino.cpp").
+	OutputBaseName string
+
+	// MainFileExtensions are the extensions of the main files that must be
+	// merged together with the other sketch files. A main file with a
+	// different extension (for example a .cpp) is passed through unchanged.
+	// If empty the extensions in globals.MainFileValidExtensions are used.
+	MainFileExtensions []string
+
+	// Jobs is the number of additional files copied in parallel in the
+	// build path. If zero the number of available CPUs is used.
+	Jobs int
+
+	// MergeExcludedFiles are the paths, relative to the sketch folder, of the
+	// sketch files that must not be merged in the main .cpp file (for
+	// example .ino templates). The files are left untouched on disk. Like the
+	// keys of the source overrides, the paths may use either the OS-native or
+	// the forward-slash separators.
+	MergeExcludedFiles []string
+
+	// LineDirectiveStyle is the syntax of the directives that map the lines
+	// of the merged .cpp file back to the original sketch files. If unset
+	// the GCC-style "#line N" directive is used.
+	LineDirectiveStyle LineDirectiveStyle
+
+	// AdditionalFilesMode selects how the additional files that are not
+	// C/C++ sources or headers (for example data assets) are placed in the
+	// build path. If linking fails the file is copied. Sources and headers
+	// are always copied since they're tagged with a #line directive.
+	AdditionalFilesMode AdditionalFilesMode
+
+	// AdditionalFilesStartLine is the line number given to the first line of
+	// some additional files in the #line directive added to their copies,
+	// keyed by path relative to the sketch folder (with either separator, as
+	// for MergeExcludedFiles). It may be used by generated sources to map the
+	// diagnostics to the lines of the file they're generated from. The files
+	// not listed start at line 1.
+	AdditionalFilesStartLine map[string]int
+
+	// KeepStaleFiles disables the removal from the build path of the copies
+	// of the additional files that are not part of the sketch anymore, with
+	// their object files, so that they are not compiled anymore. By default
+	// every file in the build path with the extension of an additional file
+	// that is not planned (see PlannedSketchFiles) is removed: KeepStaleFiles
+	// must be set by the callers that place other sources in the build path.
+	KeepStaleFiles bool
+
+	// NoPrelude disables the automatic inclusion of Arduino.h at the top of
+	// the merged sketch source. It defaults to the no_prelude key of the
+	// sketch project file.
+	NoPrelude bool
+
+	// Prologue lines are added to the merged sketch source before the
+	// sketch code, just after the Arduino.h inclusion. Epilogue lines are
+	// added after the sketch code. They may be used, for example, to include
+	// a project wide configuration header without editing the sketch.
+	Prologue []string
+	Epilogue []string
+
+	// NoMerge disables the merge of the sketch files: each of them is copied
+	// in the build path with its own name, tagged with a #line directive like
+	// the additional files, and no merged .cpp file is produced. It may be
+	// used by toolchains that handle the .ino files with their own
+	// preprocessor. The Prologue, Epilogue and NoPrelude options are ignored.
+	NoMerge bool
+
+	// SplitTranslationUnits, if set, keeps each .ino file of the sketch in
+	// its own translation unit instead of merging them together: the main file
+	// is saved as the merged .cpp file and each of the other .ino files is
+	// saved in the build path as <file>.ino.cpp, so that editing one of them
+	// recompiles only its object file. Each unit is preceded by the Arduino.h
+	// inclusion and by the prototypes of the functions it calls that are
+	// defined in the other .ino files; the units of the other .ino files,
+	// that are not processed by the sketch preprocessor, get the prototypes
+	// of their own functions too. Global variables and types shared between
+	// the .ino files must be declared in a header included by them.
+	SplitTranslationUnits bool
+
+	// GeneratePrototypes, if set, adds to the merged source the prototypes of
+	// the functions defined in the .ino files, like the sketch preprocessor
+	// does, so that the functions may be used before their definition. The
+	// prototypes are placed before the sketch code and are counted in the
+	// returned line offset. The functions with default arguments, the
+	// templates and the functions using types declared in the sketch are
+	// skipped.
+	GeneratePrototypes bool
+
+	// ReportMergeHazard, if set, enables an advisory check of the .ino files
+	// looking for constructs that may break once the files are merged (for
+	// example an `extern "C"` block split across files). Each hazard found is
+	// passed to ReportMergeHazard, the build is not stopped.
+	ReportMergeHazard func(MergeHazard)
+
+	// ReportDuplicateFunction, if set, is called with a diagnostic if the same
+	// function is defined in more than one of the merged .ino files, that
+	// would likely produce an obscure linker error. The check is heuristic
+	// (overloads, templates and definitions inside preprocessor conditionals
+	// are skipped), so the merge is not stopped unless StrictDuplicateFunctions
+	// is set.
+	ReportDuplicateFunction func(error)
+
+	// StrictDuplicateFunctions makes the merge fail with the diagnostic of
+	// ReportDuplicateFunction instead of just reporting it.
+	StrictDuplicateFunctions bool
+
+	// OnSourceMerged, if set, is called for each sketch file as it's added
+	// to the merged source, with the line of the merged source where the
+	// first line of the file has been placed. It may be used to report the
+	// progress of the sources preparation.
+	OnSourceMerged func(file *paths.Path, startLine int)
+
+	// FileReader, if set, is used to read the content of the sketch files
+	// instead of reading them from disk, for example to back the sketch with
+	// the unsaved buffers of an editor. The files are still the ones listed
+	// in the sketch, and the source overrides take precedence. When set, the
+	// additional files are always copied (see AdditionalFilesMode) and
+	// their permissions are not preserved.
+	FileReader SketchFileReader
+
+	// SourceEncoding is the encoding assumed for the .ino files that are not
+	// valid UTF-8 (for example old sketches saved in Latin-1 or
+	// Windows-1252): if set, they are converted to UTF-8 when they are
+	// merged. The files that are valid UTF-8 are always left unchanged. If
+	// unset the files are merged as they are.
+	SourceEncoding SourceEncoding
+
+	// CachePreparation, if set, makes PrepareSketchBuildPath skip the merge
+	// and the copy of the sketch files if the content of the sketch files,
+	// the source overrides, the build path and the options are the same of
+	// the last successful preparation: the results of that preparation are
+	// returned instead. The build path must not be modified by others
+	// between the preparations. When the preparation is skipped the
+	// callbacks (like OnSourceMerged) are not called.
+	CachePreparation bool
+
+	// stateMux guards stats, overridden and sourceMap, that are written
+	// during the preparation and may be read at any time
+	stateMux sync.Mutex
+	stats    SketchPreparationStats
+
+	// overridden are the sketch files taken from the source overrides in the
+	// last preparation
+	overridden []string
+
+	// sourceMap is the placement of the sketch files in the last merged source
+	sourceMap []SketchSourceMapping
+
+	// includesArduinoH caches the result of the search of the Arduino.h
+	// inclusion in the last merged main source, keyed by its hash
+	includesArduinoH    includesArduinoHResult
+	includesArduinoHMux sync.Mutex
+
+	// prepareMux makes the preparations of the build path run one at a time,
+	// it guards prepared
+	prepareMux sync.Mutex
+
+	// prepared is the result of the last successful preparation, see
+	// CachePreparation
+	prepared preparedSketch
+}
+
+// preparedSketch is the result of PrepareSketchBuildPath for the inputs with
+// the given hash
+type preparedSketch struct {
+	valid        bool
+	hash         [sha256.Size]byte
+	offset       int
+	mergedSource string
+	sourceMap    []SketchSourceMapping
+	overridden   []string
+	stats        SketchPreparationStats
+}
+
+// includesArduinoHResult is the result of SourceIncludesArduinoH for the
+// source with the given hash
+type includesArduinoHResult struct {
+	valid  bool
+	hash   [sha256.Size]byte
+	result bool
+}
+
+// SketchFileReader reads the content of the sketch files, see
+// Builder.FileReader.
+type SketchFileReader interface {
+	ReadFile(file *paths.Path) ([]byte, error)
+}
+
+// LineDirectiveStyle is the syntax used for the line directives emitted in
+// the merged sketch source.
+type LineDirectiveStyle int
+
+const (
+	// LineDirectiveGCC emits directives in the form `#line N "file"`
+	LineDirectiveGCC LineDirectiveStyle = iota
+	// LineDirectiveGNUMarker emits GNU cpp line markers in the form `# N "file"`
+	LineDirectiveGNUMarker
+)
+
+// lineDirective returns the directive, including the trailing newline,
+// stating that the next line is the line number line of file.
+func (s LineDirectiveStyle) lineDirective(line int, file *paths.Path) string {
+	prefix := "#line "
+	if s == LineDirectiveGNUMarker {
+		prefix = "# "
+	}
+	return prefix + strconv.Itoa(line) + " " + QuoteCppString(file.String()) + "\n"
+}
+
+// SourceEncoding is the encoding of the sketch files that are not valid
+// UTF-8, see Builder.SourceEncoding.
+type SourceEncoding int
+
+const (
+	// SourceEncodingPassThrough leaves the files that are not valid UTF-8
+	// unchanged
+	SourceEncodingPassThrough SourceEncoding = iota
+	// SourceEncodingLatin1 converts the files from ISO-8859-1
+	SourceEncodingLatin1
+	// SourceEncodingWindows1252 converts the files from Windows-1252
+	SourceEncodingWindows1252
+)
+
+// toUTF8 returns data converted to UTF-8 from the encoding e, unless data is
+// already valid UTF-8.
+func (e SourceEncoding) toUTF8(data []byte) ([]byte, error) {
+	if utf8.Valid(data) {
+		return data, nil
+	}
+	switch e {
+	case SourceEncodingLatin1:
+		return charmap.ISO8859_1.NewDecoder().Bytes(data)
+	case SourceEncodingWindows1252:
+		return charmap.Windows1252.NewDecoder().Bytes(data)
+	}
+	return data, nil
+}
+
+// AdditionalFilesMode is the way the sketch additional files that don't
+// need a #line directive are placed in the build path.
+type AdditionalFilesMode int
+
+const (
+	// AdditionalFilesCopy copies the files in the build path
+	AdditionalFilesCopy AdditionalFilesMode = iota
+	// AdditionalFilesHardLink creates hard links to the files in the build path
+	AdditionalFilesHardLink
+	// AdditionalFilesSymlink creates symbolic links to the files in the build path
+	AdditionalFilesSymlink
+)
+
+// NewBuilder creates a Builder for the given sketch.
+func NewBuilder(sk *sketch.Sketch) *Builder {
+	b := &Builder{sketch: sk}
+	if sk != nil && sk.Project != nil {
+		b.NoPrelude = sk.Project.NoPrelude
+	}
+	return b
+}
+
+// Sketch returns the sketch being built.
+func (b *Builder) Sketch() *sketch.Sketch {
+	b.sketchMux.RLock()
+	defer b.sketchMux.RUnlock()
+	return b.sketch
+}
+
+// SetSketch replaces the sketch being built, for example with a sketch
+// loaded again after its files have changed, so that the following
+// preparations use the new file lists. The options of the Builder are left
+// unchanged. SetSketch may be called while other goroutines are preparing
+// the sketch: it waits for the running preparations to complete.
+func (b *Builder) SetSketch(sk *sketch.Sketch) {
+	b.sketchMux.Lock()
+	defer b.sketchMux.Unlock()
+	b.sketch = sk
+}
+
+// Reload loads again the sketch from its folder and replaces the sketch
+// being built (see SetSketch). The sketch is left unchanged if it can't be
+// loaded.
+func (b *Builder) Reload() error {
+	sk, err := sketch.New(b.Sketch().FullPath)
+	if err != nil {
+		return err
+	}
+	b.SetSketch(sk)
+	return nil
+}
+
+// NeedsPreprocessing returns true if the sketch .ino files must be merged
+// and preprocessed, false if the sketch is a plain C++ project: its .ino
+// files (the ones not excluded from the merge) contain only comments and
+// blank lines and the code is in the C/C++ source files of the sketch, or
+// its main file doesn't have one of the extensions that must be merged (see
+// Builder.MainFileExtensions). In that case PrepareSketchBuildPath just
+// copies the main file and the other sketch files in the build path. If
+// the sketch files can't be read NeedsPreprocessing returns true, the
+// error is reported by the preparation.
+func (b *Builder) NeedsPreprocessing() bool {
+	b.sketchMux.RLock()
+	defer b.sketchMux.RUnlock()
+	needsPreprocessing, err := b.needsPreprocessing(nil)
+	return needsPreprocessing || err != nil
+}
+
+// needsPreprocessing works like NeedsPreprocessing, taking the sources from
+// overrides if available.
+func (b *Builder) needsPreprocessing(overrides map[string]string) (bool, error) {
+	if !b.mainFileNeedsMerge() {
+		return false, nil
+	}
+	_, sources, err := b.sketchFilesSources(overrides)
+	if err != nil {
+		return false, err
+	}
+	return !b.isPlainCppProject(sources), nil
+}
+
+// isPlainCppProject returns true if the given sources of the .ino files of
+// the sketch contain only comments and blank lines, while the sketch has
+// some C/C++ source files.
+func (b *Builder) isPlainCppProject(sources []string) bool {
+	for _, src := range sources {
+		if strings.TrimSpace(removeCommentsAndLiterals(src)) != "" {
+			return false
+		}
+	}
+	for _, file := range b.sketch.AdditionalFiles {
+		if _, ok := globals.SourceFilesValidExtensions[file.Ext()]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mainFileNeedsMerge returns true if the sketch main file has one of the
+// extensions that must be merged in a single .cpp file.
+func (b *Builder) mainFileNeedsMerge() bool {
+	ext := b.sketch.MainFile.Ext()
+	if len(b.MainFileExtensions) == 0 {
+		_, ok := globals.MainFileValidExtensions[ext]
+		return ok
+	}
+	for _, mainExt := range b.MainFileExtensions {
+		if ext == mainExt {
+			return true
+		}
+	}
+	return false
+}
+
+// isMergeExcluded returns true if the sketch file at relpath has been
+// excluded from the merge (see Builder.MergeExcludedFiles).
+func (b *Builder) isMergeExcluded(relpath *paths.Path) bool {
+	key := overrideKey(relpath.String())
+	for _, excluded := range b.MergeExcludedFiles {
+		if overrideKey(excluded) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// additionalFileStartLine returns the line number given to the first line of
+// the additional file at relpath, see Builder.AdditionalFilesStartLine. If
+// both forms of the same path are set, the forward-slash one wins.
+func (b *Builder) additionalFileStartLine(relpath *paths.Path) int {
+	key := overrideKey(relpath.String())
+	if line, ok := b.AdditionalFilesStartLine[key]; ok {
+		return line
+	}
+	for file, line := range b.AdditionalFilesStartLine {
+		if overrideKey(file) == key {
+			return line
+		}
+	}
+	return 1
+}
+
+// mergedFileName returns the name of the .cpp file produced by the merge of
+// the sketch sources.
+func (b *Builder) mergedFileName() string {
+	if b.OutputBaseName != "" {
+		return b.OutputBaseName + ".cpp"
+	}
+	if b.sketch.MainFile.Ext() == ".cpp" {
+		return b.sketch.MainFile.Base()
+	}
+	return b.sketch.MainFile.Base() + ".cpp"
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sync"
 
 	"github.com/arduino/go-paths-helper"
 )
@@ -28,6 +29,7 @@ import (
 type CompilationDatabase struct {
 	Contents []CompilationCommand
 	File     *paths.Path
+	lock     sync.Mutex
 }
 
 // CompilationCommand keeps track of a single run of a compile command
@@ -81,7 +83,8 @@ func dirForCommand(command *exec.Cmd) string {
 	return dir
 }
 
-// Add adds a new CompilationDatabase entry
+// Add adds a new CompilationDatabase entry, it is safe to call Add
+// concurrently from multiple goroutines.
 func (db *CompilationDatabase) Add(target *paths.Path, command *exec.Cmd) {
 	entry := CompilationCommand{
 		Directory: dirForCommand(command),
@@ -89,5 +92,7 @@ func (db *CompilationDatabase) Add(target *paths.Path, command *exec.Cmd) {
 		File:      target.String(),
 	}
 
+	db.lock.Lock()
 	db.Contents = append(db.Contents, entry)
+	db.lock.Unlock()
 }
@@ -0,0 +1,234 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/arduino/go-paths-helper"
+)
+
+// functionHeader matches the text preceding the body of a function
+// definition: return type, (possibly qualified) name and parameters list
+var functionHeader = regexp.MustCompile(`^([\w:<>,*&\s]*[\s*&])((?:[A-Za-z_]\w*::)*[A-Za-z_]\w*)\s*\(([^()]*)\)\s*(?:const\s*)?$`)
+
+// functionDefinition is a top level function definition found in a sketch file
+type functionDefinition struct {
+	file *paths.Path
+	line int
+}
+
+// checkDuplicateFunctions looks for functions defined in more than one of the
+// given .ino files, that would produce a linker error once merged. The check
+// is conservative: only definitions with the same name and the same parameters
+// list, outside of any preprocessor conditional, are reported. Static, inline
+// and template functions are ignored.
+func checkDuplicateFunctions(files paths.PathList, sources []string) error {
+	defined := map[string]functionDefinition{}
+	for i, file := range files {
+		for _, f := range findFunctionDefinitions(sources[i]) {
+			if prev, ok := defined[f.signature]; ok && !prev.file.EquivalentTo(file) {
+				return fmt.Errorf(tr("function %[1]s is defined both in %[2]s:%[3]d and in %[4]s:%[5]d"),
+					f.name, prev.file, prev.line, file, f.line)
+			}
+			defined[f.signature] = functionDefinition{file: file, line: f.line}
+		}
+	}
+	return nil
+}
+
+type foundFunction struct {
+	name      string
+	signature string
+	// prototype is the declaration of the function, as written in the
+	// definition (for example "void blink(int times);")
+	prototype string
+	line      int
+}
+
+// findFunctionDefinitions returns the function definitions found at the top
+// level of src, see checkDuplicateFunctions.
+func findFunctionDefinitions(src string) []foundFunction {
+	res := []foundFunction{}
+	src = removeCommentsAndLiterals(src)
+
+	depth := 0
+	conditionals := 0
+	statement := ""
+	statementLine := 0
+	continuedDirective := false
+	for n, line := range strings.Split(src, "\n") {
+		if continuedDirective {
+			continuedDirective = strings.HasSuffix(strings.TrimRight(line, "\r"), "\\")
+			continue
+		}
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") {
+			continuedDirective = strings.HasSuffix(strings.TrimRight(line, "\r"), "\\")
+			directive := strings.TrimSpace(trimmed[1:])
+			if strings.HasPrefix(directive, "if") {
+				conditionals++
+			} else if strings.HasPrefix(directive, "endif") && conditionals > 0 {
+				conditionals--
+			}
+			continue
+		}
+		for _, c := range line {
+			switch c {
+			case '{':
+				if depth == 0 && conditionals == 0 {
+					if f, ok := parseFunctionHeader(statement); ok {
+						f.line = statementLine
+						res = append(res, f)
+					}
+				}
+				depth++
+				statement = ""
+			case '}':
+				if depth > 0 {
+					depth--
+				}
+				statement = ""
+			case ';':
+				statement = ""
+			default:
+				if depth == 0 {
+					if strings.TrimSpace(statement) == "" && c != ' ' && c != '\t' {
+						statementLine = n + 1
+					}
+					statement += string(c)
+				}
+			}
+		}
+		if depth == 0 {
+			statement += " "
+		}
+	}
+	return res
+}
+
+// parseFunctionHeader checks if header is the beginning of the definition of a
+// function that may be defined only once in the merged sketch.
+func parseFunctionHeader(header string) (foundFunction, bool) {
+	header = strings.Join(strings.Fields(header), " ")
+	m := functionHeader.FindStringSubmatch(header)
+	if m == nil {
+		return foundFunction{}, false
+	}
+	for _, word := range strings.Fields(m[1]) {
+		switch word {
+		case "static", "inline", "template", "extern", "typedef", "class", "struct", "union", "enum", "namespace":
+			return foundFunction{}, false
+		}
+	}
+	if strings.ContainsAny(m[1], "<>") {
+		// a template specialization or a complex return type, better be safe
+		return foundFunction{}, false
+	}
+	name := m[2]
+	params := strings.ReplaceAll(strings.TrimSpace(m[3]), " ", "")
+	if params == "void" {
+		params = ""
+	}
+	return foundFunction{
+		name:      name,
+		signature: name + "(" + params + ")",
+		prototype: strings.TrimSpace(m[1]) + " " + name + "(" + strings.TrimSpace(m[3]) + ");",
+	}, true
+}
+
+// removeCommentsAndLiterals replaces comments, string and char literals in
+// src with spaces, keeping the newlines so that line numbers are preserved.
+func removeCommentsAndLiterals(src string) string {
+	var res strings.Builder
+	const (
+		code = iota
+		lineComment
+		blockComment
+		stringLiteral
+		charLiteral
+	)
+	state := code
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		next := byte(0)
+		if i+1 < len(src) {
+			next = src[i+1]
+		}
+		switch state {
+		case code:
+			switch {
+			case c == '/' && next == '/':
+				state = lineComment
+			case c == '/' && next == '*':
+				state = blockComment
+				res.WriteString("  ")
+				i++
+				continue
+			case c == '"':
+				state = stringLiteral
+			case c == '\'':
+				state = charLiteral
+			default:
+				res.WriteByte(c)
+				continue
+			}
+			res.WriteByte(' ')
+		case lineComment:
+			if c == '\n' {
+				state = code
+				res.WriteByte(c)
+			} else {
+				res.WriteByte(' ')
+			}
+		case blockComment:
+			if c == '*' && next == '/' {
+				state = code
+				res.WriteString("  ")
+				i++
+			} else if c == '\n' {
+				res.WriteByte(c)
+			} else {
+				res.WriteByte(' ')
+			}
+		case stringLiteral, charLiteral:
+			quote := byte('"')
+			if state == charLiteral {
+				quote = '\''
+			}
+			if c == '\\' && next != 0 {
+				res.WriteByte(' ')
+				if next == '\n' {
+					res.WriteByte('\n')
+				} else {
+					res.WriteByte(' ')
+				}
+				i++
+				continue
+			}
+			if c == quote || c == '\n' {
+				state = code
+			}
+			if c == '\n' {
+				res.WriteByte(c)
+			} else {
+				res.WriteByte(' ')
+			}
+		}
+	}
+	return res.String()
+}
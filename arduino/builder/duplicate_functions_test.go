@@ -0,0 +1,121 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/go-paths-helper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindFunctionDefinitions(t *testing.T) {
+	src := `#include <Arduino.h>
+// void commented() {}
+const char *s = "void inString() {";
+int counter = 0;
+
+void setup() {
+  if (counter) { counter++; }
+}
+
+unsigned long
+  compute(int a,
+          int b) {
+  return a + b;
+}
+
+static void helper() {}
+inline int twice(int a) { return a * 2; }
+template <typename T> T id(T v) { return v; }
+void Foo::bar() const {}
+struct Point { int x; int y; };
+#ifdef DEBUG
+void debugOnly() {}
+#endif
+#define BODY(x) \
+  void macro##x() {}
+void loop(void) {}
+`
+	found := findFunctionDefinitions(src)
+	require.Equal(t, []foundFunction{
+		{name: "setup", signature: "setup()", prototype: "void setup();", line: 6},
+		{name: "compute", signature: "compute(inta,intb)", prototype: "unsigned long compute(int a, int b);", line: 10},
+		{name: "Foo::bar", signature: "Foo::bar()", prototype: "void Foo::bar();", line: 19},
+		{name: "loop", signature: "loop()", prototype: "void loop(void);", line: 26},
+	}, found)
+}
+
+func TestCheckDuplicateFunctions(t *testing.T) {
+	files := paths.NewPathList("a.ino", "b.ino")
+
+	// overloads are not duplicates
+	require.NoError(t, checkDuplicateFunctions(files, []string{
+		"void f(int a) {}\n",
+		"void f(float a) {}\n",
+	}))
+
+	// same function in different classes
+	require.NoError(t, checkDuplicateFunctions(files, []string{
+		"void A::f() {}\n",
+		"void B::f() {}\n",
+	}))
+
+	// static functions are ignored
+	require.NoError(t, checkDuplicateFunctions(files, []string{
+		"static void f() {}\n",
+		"static void f() {}\n",
+	}))
+
+	err := checkDuplicateFunctions(files, []string{
+		"void setup() {}\n\nint helper(int a) {\n  return a;\n}\n",
+		"// helpers\nint   helper( int a ) { return a; }\n",
+	})
+	require.EqualError(t, err, "function helper is defined both in a.ino:3 and in b.ino:2")
+}
+
+func TestMergeSketchSourcesDuplicateFunctions(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	mainFile := sketchPath.Join(sketchPath.Base() + ".ino")
+	otherFile := sketchPath.Join("other.ino")
+	require.NoError(t, mainFile.WriteFile([]byte("void setup() {}\nvoid loop() {}\nvoid blink() {}\n")))
+	require.NoError(t, otherFile.WriteFile([]byte("void blink() {}\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	// by default the duplicates are only reported
+	var reported []error
+	b := NewBuilder(s)
+	b.ReportDuplicateFunction = func(err error) { reported = append(reported, err) }
+	_, _, _, err = b.sketchMergeSources(nil)
+	require.NoError(t, err)
+	require.Len(t, reported, 1)
+	require.Contains(t, reported[0].Error(), "function blink is defined both in "+mainFile.String()+":3 and in "+otherFile.String()+":1")
+
+	b.StrictDuplicateFunctions = true
+	_, _, _, err = b.sketchMergeSources(nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "function blink is defined both in "+mainFile.String()+":3 and in "+otherFile.String()+":1")
+
+	// excluded files are not merged, so they're not checked
+	b = NewBuilder(s)
+	b.StrictDuplicateFunctions = true
+	b.MergeExcludedFiles = []string{"other.ino"}
+	_, _, _, err = b.sketchMergeSources(nil)
+	require.NoError(t, err)
+}
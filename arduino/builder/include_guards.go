@@ -0,0 +1,91 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/arduino/arduino-cli/arduino/globals"
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/go-paths-helper"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	pragmaOnce     = regexp.MustCompile(`^#\s*pragma\s+once\b`)
+	guardCondition = regexp.MustCompile(`^#\s*(?:ifndef\s+(\w+)|if\s+!\s*defined\s*\(?\s*(\w+)\s*\)?)\s*$`)
+	guardDefine    = regexp.MustCompile(`^#\s*define\s+(\w+)\b`)
+)
+
+// HeadersWithoutIncludeGuard returns the header files of the sketch that have
+// neither an include guard nor a #pragma once directive. Including them more
+// than once in the merged sketch may cause redefinition errors. The check is
+// advisory: the headers that can't be read are skipped.
+func HeadersWithoutIncludeGuard(sk *sketch.Sketch, overrides map[string]string) paths.PathList {
+	overrides = normalizeOverrides(overrides)
+	res := paths.PathList{}
+	for _, file := range sk.AdditionalFiles {
+		if _, ok := globals.HeaderFilesValidExtensions[file.Ext()]; !ok {
+			continue
+		}
+		relpath, err := sk.FullPath.RelTo(file)
+		if err != nil {
+			continue
+		}
+		src, ok := overrides[overrideKey(relpath.String())]
+		if !ok {
+			data, err := file.ReadFile()
+			if err != nil {
+				logrus.WithError(err).Debugf("Could not check include guard of %s", file)
+				continue
+			}
+			src = string(data)
+		}
+		if !HasIncludeGuard(src) {
+			res.Add(file)
+		}
+	}
+	return res
+}
+
+// HasIncludeGuard returns true if the given header source contains a #pragma
+// once directive, or if it starts with an include guard in the form:
+//
+//	#ifndef NAME
+//	#define NAME
+func HasIncludeGuard(src string) bool {
+	lines := []string{}
+	for _, line := range strings.Split(removeCommentsAndLiterals(src), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if pragmaOnce.MatchString(line) {
+			return true
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) < 2 {
+		return false
+	}
+	condition := guardCondition.FindStringSubmatch(lines[0])
+	if condition == nil {
+		return false
+	}
+	name := condition[1] + condition[2]
+	define := guardDefine.FindStringSubmatch(lines[1])
+	return define != nil && define[1] == name
+}
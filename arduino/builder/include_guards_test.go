@@ -0,0 +1,54 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasIncludeGuard(t *testing.T) {
+	require.True(t, HasIncludeGuard("#pragma once\nint a;\n"))
+	require.True(t, HasIncludeGuard("// Copyright\n/* header */\n#  pragma once\n"))
+	require.True(t, HasIncludeGuard("#ifndef HELPER_H\n#define HELPER_H\nint a;\n#endif\n"))
+	require.True(t, HasIncludeGuard("/*\n * license\n */\n\n#if !defined(HELPER_H)\n#define HELPER_H 1\n#endif\n"))
+	require.False(t, HasIncludeGuard(""))
+	require.False(t, HasIncludeGuard("int a;\n"))
+	require.False(t, HasIncludeGuard("// #pragma once\nint a;\n"))
+	require.False(t, HasIncludeGuard("#ifndef HELPER_H\n#define OTHER_H\n#endif\n"))
+	require.False(t, HasIncludeGuard("#include <Arduino.h>\n#ifndef HELPER_H\n#define HELPER_H\n#endif\n"))
+}
+
+func TestHeadersWithoutIncludeGuard(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	require.NoError(t, sketchPath.Join(sketchPath.Base()+".ino").WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	require.NoError(t, sketchPath.Join("guarded.h").WriteFile([]byte("#pragma once\n")))
+	require.NoError(t, sketchPath.Join("unguarded.h").WriteFile([]byte("int a;\n")))
+	require.NoError(t, sketchPath.Join("source.cpp").WriteFile([]byte("int b;\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	headers := HeadersWithoutIncludeGuard(s, nil)
+	require.Equal(t, []string{sketchPath.Join("unguarded.h").String()}, headers.AsStrings())
+
+	// overrides are checked in place of the files on disk
+	overrides := map[string]string{"unguarded.h": "#pragma once\nint a;\n", "guarded.h": "int c;\n"}
+	headers = HeadersWithoutIncludeGuard(s, overrides)
+	require.Equal(t, []string{sketchPath.Join("guarded.h").String()}, headers.AsStrings())
+}
@@ -0,0 +1,133 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/arduino/go-paths-helper"
+)
+
+// externCBlock matches the opening of an `extern "C" {` block once the string
+// literals have been removed from the source
+var externCBlock = regexp.MustCompile(`\bextern\s+\{`)
+
+// MergeHazard is a construct of a sketch file that may not work as expected
+// once the .ino files are merged together, see Builder.ReportMergeHazard.
+type MergeHazard struct {
+	File    *paths.Path
+	Line    int
+	Message string
+}
+
+func (h MergeHazard) String() string {
+	return fmt.Sprintf("%s:%d: %s", h.File, h.Line, h.Message)
+}
+
+// findMergeHazards looks for the constructs of the given .ino files, in merge
+// order, that may break once the files are merged:
+//   - blocks, like `extern "C"` ones, opened in a file and closed in another
+//   - functions of the sketch called to initialize a global variable placed
+//     before the first function definition, where the prototypes of the
+//     sketch functions are added by the preprocessor
+//
+// The check is heuristic, it's meant to help understanding the compile errors.
+func findMergeHazards(files paths.PathList, sources []string) []MergeHazard {
+	res := []MergeHazard{}
+	stripped := make([]string, len(sources))
+	functions := map[string]bool{}
+	for i, src := range sources {
+		stripped[i] = removeCommentsAndLiterals(src)
+		res = append(res, findUnbalancedBlocks(files[i], stripped[i])...)
+		for _, f := range findFunctionDefinitions(src) {
+			functions[f.name] = true
+		}
+	}
+	if len(functions) == 0 {
+		return res
+	}
+
+	// function calls in the global initializers before the prototypes
+	names := []string{}
+	for name := range functions {
+		names = append(names, regexp.QuoteMeta(name))
+	}
+	call := regexp.MustCompile(`=[^;{}]*\b(` + strings.Join(names, "|") + `)\s*\(`)
+	for i, src := range stripped {
+		lines := strings.Split(src, "\n")
+		defs := findFunctionDefinitions(sources[i])
+		if len(defs) > 0 {
+			lines = lines[:defs[0].line-1]
+		}
+		depth := 0
+		for n, line := range lines {
+			if depth == 0 && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+				if m := call.FindStringSubmatch(line); m != nil {
+					res = append(res, MergeHazard{
+						File:    files[i],
+						Line:    n + 1,
+						Message: tr("function %s is called before its prototype", m[1]),
+					})
+				}
+			}
+			depth += strings.Count(line, "{") - strings.Count(line, "}")
+		}
+		if len(defs) > 0 {
+			// the prototypes are placed before the first function definition
+			break
+		}
+	}
+	return res
+}
+
+// findUnbalancedBlocks reports the blocks of src that are closed without
+// being opened, or that are left open at the end of the file.
+func findUnbalancedBlocks(file *paths.Path, src string) []MergeHazard {
+	res := []MergeHazard{}
+	type block struct {
+		line    int
+		externC bool
+	}
+	open := []block{}
+	for n, line := range strings.Split(src, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		externC := externCBlock.FindStringIndex(line)
+		for i, c := range line {
+			switch c {
+			case '{':
+				open = append(open, block{line: n + 1, externC: externC != nil && i == externC[1]-1})
+			case '}':
+				if len(open) == 0 {
+					res = append(res, MergeHazard{File: file, Line: n + 1, Message: tr("closing a block opened in another file")})
+					continue
+				}
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	for _, b := range open {
+		msg := tr("block not closed in the same file")
+		if b.externC {
+			msg = tr("extern \"C\" block not closed in the same file")
+		}
+		res = append(res, MergeHazard{File: file, Line: b.line, Message: msg})
+	}
+	return res
+}
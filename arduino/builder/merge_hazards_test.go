@@ -0,0 +1,81 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/go-paths-helper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindMergeHazards(t *testing.T) {
+	files := paths.NewPathList("a.ino", "b.ino")
+
+	// a well formed sketch
+	require.Empty(t, findMergeHazards(files, []string{
+		"extern \"C\" {\nvoid f();\n}\nint x = 1;\nvoid setup() { x = compute(); }\n",
+		"int compute() { return 1; }\n// extern \"C\" {\nconst char *s = \"}\";\n",
+	}))
+
+	// extern "C" block split across files
+	hazards := findMergeHazards(files, []string{
+		"void setup() {}\nextern \"C\" {\nvoid f();\n",
+		"void g();\n}\n",
+	})
+	require.Equal(t, []MergeHazard{
+		{File: files[0], Line: 2, Message: "extern \"C\" block not closed in the same file"},
+		{File: files[1], Line: 2, Message: "closing a block opened in another file"},
+	}, hazards)
+
+	// function used in a global initializer before the prototypes
+	hazards = findMergeHazards(files, []string{
+		"#define N 1\nint x = compute(N);\nint y = 2;\n",
+		"int z = compute(2);\nvoid setup() {}\nint w = compute(3);\nint compute(int a) { return a; }\n",
+	})
+	require.Equal(t, []MergeHazard{
+		{File: files[0], Line: 2, Message: "function compute is called before its prototype"},
+		{File: files[1], Line: 1, Message: "function compute is called before its prototype"},
+	}, hazards)
+	require.Equal(t, "a.ino:2: function compute is called before its prototype", hazards[0].String())
+}
+
+func TestMergeSketchSourcesReportsMergeHazards(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	mainFile := sketchPath.Join(sketchPath.Base() + ".ino")
+	otherFile := sketchPath.Join("other.ino")
+	require.NoError(t, mainFile.WriteFile([]byte("void setup() {}\nvoid loop() {}\nextern \"C\" {\n")))
+	require.NoError(t, otherFile.WriteFile([]byte("void f();\n}\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	// the check is disabled by default
+	_, _, _, err = NewBuilder(s).sketchMergeSources(nil)
+	require.NoError(t, err)
+
+	// hazards are only reported, the merge succeeds
+	hazards := []MergeHazard{}
+	b := NewBuilder(s)
+	b.ReportMergeHazard = func(h MergeHazard) { hazards = append(hazards, h) }
+	_, _, _, err = b.sketchMergeSources(nil)
+	require.NoError(t, err)
+	require.Len(t, hazards, 2)
+	require.True(t, hazards[0].File.EquivalentTo(mainFile))
+	require.Equal(t, 3, hazards[0].Line)
+	require.True(t, hazards[1].File.EquivalentTo(otherFile))
+}
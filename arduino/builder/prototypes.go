@@ -0,0 +1,65 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sketchTypeDeclaration matches the names of the types declared in a sketch
+var sketchTypeDeclaration = regexp.MustCompile(`\b(?:(?:struct|class|union|enum(?:\s+class)?)\s+([A-Za-z_]\w*)|using\s+([A-Za-z_]\w*)\s*=|typedef\b[^;{]*?([A-Za-z_]\w*)\s*;)`)
+
+// sketchPrototypes returns the prototypes of the functions defined in the
+// given sources, in order of definition, see Builder.GeneratePrototypes.
+// The functions found by findFunctionDefinitions are skipped (static, inline
+// and template functions are already left out) if they:
+//   - are class members
+//   - have parameters with default arguments, that can't be repeated
+//   - use a type declared in the sketch, that is not known yet where the
+//     prototypes are placed
+func sketchPrototypes(sources []string) []string {
+	sketchTypes := []string{}
+	for _, src := range sources {
+		for _, m := range sketchTypeDeclaration.FindAllStringSubmatch(removeCommentsAndLiterals(src), -1) {
+			for _, name := range m[1:] {
+				if name != "" {
+					sketchTypes = append(sketchTypes, regexp.QuoteMeta(name))
+				}
+			}
+		}
+	}
+	var usesSketchType *regexp.Regexp
+	if len(sketchTypes) > 0 {
+		usesSketchType = regexp.MustCompile(`\b(?:` + strings.Join(sketchTypes, "|") + `)\b`)
+	}
+
+	res := []string{}
+	declared := map[string]bool{}
+	for _, src := range sources {
+		for _, f := range findFunctionDefinitions(src) {
+			if declared[f.signature] || strings.Contains(f.name, "::") || strings.Contains(f.prototype, "=") {
+				continue
+			}
+			if usesSketchType != nil && usesSketchType.MatchString(f.prototype) {
+				continue
+			}
+			res = append(res, f.prototype)
+			declared[f.signature] = true
+		}
+	}
+	return res
+}
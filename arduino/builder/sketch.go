@@ -17,142 +17,1144 @@ package builder
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"io"
+	"os"
+	"path"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/arduino/arduino-cli/arduino/globals"
 	"github.com/arduino/arduino-cli/arduino/sketch"
 	"github.com/arduino/arduino-cli/i18n"
 	"github.com/arduino/go-paths-helper"
 	"github.com/arduino/go-properties-orderedmap"
 
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/exp/slices"
 )
 
 var (
 	includesArduinoH = regexp.MustCompile(`(?m)^\s*#\s*include\s*[<\"]Arduino\.h[>\"]`)
+	functionCall     = regexp.MustCompile(`\b([A-Za-z_]\w*)\s*\(`)
 	tr               = i18n.Tr
 )
 
+// SketchSourceMapping describes where a sketch source file has been placed
+// inside the merged .cpp file.
+type SketchSourceMapping struct {
+	// File is the original sketch source file
+	File *paths.Path
+	// StartLineInMerged is the line of the merged .cpp file (1-based) where
+	// the content of File begins, just after its line directive
+	StartLineInMerged int
+	// OriginalLineCount is the number of lines of File
+	OriginalLineCount int
+}
+
+// SketchPreparationStats reports the amount of data saved in the build path
+// by PrepareSketchBuildPath.
+type SketchPreparationStats struct {
+	// FilesCopied is the number of files saved in the build path, including
+	// the merged sketch source
+	FilesCopied int
+	// BytesWritten is the total size of the files saved in the build path.
+	// Files already up to date are counted even if they are not rewritten.
+	BytesWritten int64
+}
+
+// PrepareSketchBuildPath copies the sketch source files in the build path
+// using the default Builder options (see Builder.PrepareSketchBuildPath).
+func PrepareSketchBuildPath(sketch *sketch.Sketch, sourceOverrides map[string]string, buildPath *paths.Path) (offset int, mergedSource string, err error) {
+	return NewBuilder(sketch).PrepareSketchBuildPath(sourceOverrides, buildPath)
+}
+
+// PrepareSketchBuildPathWithSourceMap works like PrepareSketchBuildPath but
+// it also returns where each .ino file has been placed in the merged .cpp file.
+func PrepareSketchBuildPathWithSourceMap(sketch *sketch.Sketch, sourceOverrides map[string]string, buildPath *paths.Path) (offset int, mergedSource string, sourceMap []SketchSourceMapping, err error) {
+	return NewBuilder(sketch).PrepareSketchBuildPathWithSourceMap(sourceOverrides, buildPath)
+}
+
 // PrepareSketchBuildPath copies the sketch source files in the build path.
 // The .ino files are merged together to create a .cpp file (by the way, the
-// .cpp file still needs to be Arduino-preprocessed to compile).
-func PrepareSketchBuildPath(sketch *sketch.Sketch, sourceOverrides map[string]string, buildPath *paths.Path) (offset int, mergedSource string, err error) {
-	if offset, mergedSource, err = sketchMergeSources(sketch, sourceOverrides); err != nil {
-		return
+// .cpp file still needs to be Arduino-preprocessed to compile), unless
+// Builder.NoMerge is set: in that case the .ino files are copied one by one
+// and the returned merged source is empty. The copies of the files removed
+// from the sketch are deleted, unless Builder.KeepStaleFiles is set.
+// If the sketch doesn't need preprocessing (see Builder.NeedsPreprocessing)
+// the main file is copied as it is, without merging.
+// The build path is complete once PrepareSketchBuildPath returns: the sketch
+// sources are not written again during the compile, so the build path may
+// be made read-only as long as the object files are written elsewhere.
+func (b *Builder) PrepareSketchBuildPath(sourceOverrides map[string]string, buildPath *paths.Path) (offset int, mergedSource string, err error) {
+	offset, mergedSource, _, err = b.PrepareSketchBuildPathWithSourceMap(sourceOverrides, buildPath)
+	return
+}
+
+// PrepareSketchBuildPathWithSourceMap works like PrepareSketchBuildPath but
+// it also returns where each .ino file has been placed in the merged .cpp file.
+func (b *Builder) PrepareSketchBuildPathWithSourceMap(sourceOverrides map[string]string, buildPath *paths.Path) (offset int, mergedSource string, sourceMap []SketchSourceMapping, err error) {
+	b.sketchMux.RLock()
+	defer b.sketchMux.RUnlock()
+	b.prepareMux.Lock()
+	defer b.prepareMux.Unlock()
+	sourceOverrides = normalizeOverrides(sourceOverrides)
+	b.stateMux.Lock()
+	b.stats = SketchPreparationStats{}
+	b.overridden = nil
+	b.stateMux.Unlock()
+
+	var hash [sha256.Size]byte
+	if b.CachePreparation {
+		var hashErr error
+		hash, hashErr = b.preparationHash(sourceOverrides, buildPath)
+		if hashErr == nil && b.prepared.valid && b.prepared.hash == hash && b.preparedOutputExists(buildPath) {
+			b.stateMux.Lock()
+			b.overridden = append([]string{}, b.prepared.overridden...)
+			b.sourceMap = b.prepared.sourceMap
+			b.stats = b.prepared.stats
+			b.stateMux.Unlock()
+			return b.prepared.offset, b.prepared.mergedSource, b.prepared.sourceMap, nil
+		}
+		// if the hash can't be computed the preparation below reports
+		// the error, if any
+		b.prepared = preparedSketch{valid: hashErr == nil, hash: hash}
 	}
-	if err = SketchSaveItemCpp(sketch.MainFile, []byte(mergedSource), buildPath); err != nil {
-		return
+	defer func() {
+		if !b.prepared.valid {
+			return
+		}
+		if err != nil {
+			b.prepared = preparedSketch{}
+			return
+		}
+		b.prepared.offset = offset
+		b.prepared.mergedSource = mergedSource
+		b.prepared.sourceMap = sourceMap
+		b.prepared.overridden = b.OverriddenFiles()
+		b.prepared.stats = b.PreparationStats()
+	}()
+
+	var files paths.PathList
+	var sources []string
+	if b.mainFileNeedsMerge() {
+		if files, sources, err = b.sketchFilesSources(sourceOverrides); err != nil {
+			return
+		}
+	}
+	if !b.mainFileNeedsMerge() || b.isPlainCppProject(sources) {
+		if mergedSource, sourceMap, err = b.sketchCopyMainFile(buildPath, sourceOverrides); err != nil {
+			return
+		}
+	} else if b.NoMerge {
+		if err = b.sketchCopySketchFiles(buildPath, sourceOverrides); err != nil {
+			return
+		}
+	} else {
+		var merged strings.Builder
+		if offset, sourceMap, err = b.sketchMergeFilesTo(&merged, files, sources); err != nil {
+			return
+		}
+		mergedSource = merged.String()
+		if b.SplitTranslationUnits {
+			if err = b.sketchSaveTranslationUnits(buildPath, sourceOverrides, files, sources); err != nil {
+				return
+			}
+		}
+		for _, m := range sourceMap {
+			if relpath, err := b.sketch.FullPath.RelTo(m.File); err == nil {
+				if _, ok := sourceOverrides[overrideKey(relpath.String())]; ok {
+					b.addOverridden(relpath)
+				}
+			}
+		}
+		if err = saveCpp(buildPath.Join(b.mergedFileName()), []byte(mergedSource), buildPath); err != nil {
+			return
+		}
+		b.addToStats(len(mergedSource))
 	}
-	if err = sketchCopyAdditionalFiles(sketch, buildPath, sourceOverrides); err != nil {
+	if err = b.sketchCopyAdditionalFiles(buildPath, sourceOverrides); err != nil {
 		return
 	}
+	if !b.KeepStaleFiles {
+		err = b.removeStaleAdditionalFiles(buildPath)
+	}
 	return
 }
 
+// PlannedSketchFiles returns the paths of the files that PrepareSketchBuildPath
+// places in buildPath, with the current options, without writing anything:
+// the merged sketch source (or the copies of the sketch files if
+// Builder.NoMerge is set), the translation units of the other sketch files
+// if Builder.SplitTranslationUnits is set, and the copies of the additional
+// files.
+func (b *Builder) PlannedSketchFiles(buildPath *paths.Path) (paths.PathList, error) {
+	b.sketchMux.RLock()
+	defer b.sketchMux.RUnlock()
+	return b.plannedSketchFiles(buildPath)
+}
+
+func (b *Builder) plannedSketchFiles(buildPath *paths.Path) (paths.PathList, error) {
+	sk := b.sketch
+	res := paths.NewPathList()
+	addCopy := func(file *paths.Path, suffix string, skipExcluded bool) error {
+		relpath, err := sk.FullPath.RelTo(file)
+		if err != nil {
+			return &SketchFileError{File: file, Message: tr("unable to compute relative path to the sketch for the item"), Cause: err}
+		}
+		if skipExcluded && b.isMergeExcluded(relpath) {
+			return nil
+		}
+		res.Add(paths.New(buildPath.JoinPath(relpath).String() + suffix))
+		return nil
+	}
+
+	needsPreprocessing, err := b.needsPreprocessing(nil)
+	if err != nil {
+		return nil, err
+	}
+	if !needsPreprocessing {
+		res.Add(buildPath.Join(b.mergedFileName()))
+	} else if b.NoMerge {
+		for _, file := range append(paths.PathList{sk.MainFile}, sk.OtherSketchFiles...) {
+			if err := addCopy(file, "", true); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		res.Add(buildPath.Join(b.mergedFileName()))
+		if b.SplitTranslationUnits {
+			for _, file := range sk.OtherSketchFiles {
+				if err := addCopy(file, ".cpp", true); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for _, file := range sk.AdditionalFiles {
+		if err := addCopy(file, "", false); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// preparationHash returns the hash of the inputs of PrepareSketchBuildPath:
+// the content of the sketch files, the source overrides, the build path and
+// the options changing the output.
+func (b *Builder) preparationHash(sourceOverrides map[string]string, buildPath *paths.Path) ([sha256.Size]byte, error) {
+	h := sha256.New()
+	fmt.Fprintln(h, buildPath, b.OutputBaseName, b.MainFileExtensions, b.MergeExcludedFiles,
+		b.LineDirectiveStyle, b.AdditionalFilesMode, b.AdditionalFilesStartLine, b.KeepStaleFiles,
+		b.NoPrelude, b.Prologue, b.Epilogue, b.NoMerge, b.SplitTranslationUnits, b.GeneratePrototypes,
+		b.SourceEncoding, b.StrictDuplicateFunctions)
+
+	files := paths.NewPathList()
+	files.Add(b.sketch.MainFile)
+	files.AddAll(b.sketch.OtherSketchFiles)
+	files.AddAll(b.sketch.AdditionalFiles)
+	for _, file := range files {
+		data, err := b.readFile(file)
+		if err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		fmt.Fprintln(h, file, len(data))
+		h.Write(data)
+	}
+
+	overridden := make([]string, 0, len(sourceOverrides))
+	for file := range sourceOverrides {
+		overridden = append(overridden, file)
+	}
+	sort.Strings(overridden)
+	for _, file := range overridden {
+		fmt.Fprintln(h, file, len(sourceOverrides[file]))
+		io.WriteString(h, sourceOverrides[file])
+	}
+
+	var hash [sha256.Size]byte
+	copy(hash[:], h.Sum(nil))
+	return hash, nil
+}
+
+// preparedOutputExists returns true if the merged source of the last
+// preparation is still in the build path.
+func (b *Builder) preparedOutputExists(buildPath *paths.Path) bool {
+	if len(b.prepared.sourceMap) == 0 {
+		// the .ino files have been copied without saving a merged source,
+		// see Builder.NoMerge
+		return true
+	}
+	return buildPath.Join(b.mergedFileName()).Exist()
+}
+
+// MergedSketchSource returns the result of the merge of the sketch .ino
+// files, as done by PrepareSketchBuildPath, without writing anything on disk.
+// The returned offset is the number of lines added before the sketch code.
+func (b *Builder) MergedSketchSource(sourceOverrides map[string]string) (offset int, mergedSource string, err error) {
+	b.sketchMux.RLock()
+	defer b.sketchMux.RUnlock()
+	offset, mergedSource, _, err = b.sketchMergeSources(normalizeOverrides(sourceOverrides))
+	return
+}
+
+// WriteMergedSketchSource works like MergedSketchSource, but the merged
+// source is written to w as it's produced instead of being kept in memory.
+// This may be used to save the merged source of big sketches.
+func (b *Builder) WriteMergedSketchSource(w io.Writer, sourceOverrides map[string]string) (offset int, err error) {
+	b.sketchMux.RLock()
+	defer b.sketchMux.RUnlock()
+	offset, _, err = b.sketchMergeSourcesTo(w, normalizeOverrides(sourceOverrides))
+	return
+}
+
+// RemapDiagnostic translates a line of the last merged sketch source (see
+// PrepareSketchBuildPath and MergedSketchSource) to the original sketch file
+// and line, so that compiler diagnostics may be reported against the files
+// edited by the user. It returns nil if the line doesn't belong to any sketch
+// file, for example if it's one of the lines added by the merge.
+// The lines added later by the sketch preprocessor are not taken into account.
+func (b *Builder) RemapDiagnostic(mergedLine int) (*paths.Path, int) {
+	for _, m := range b.lastSourceMap() {
+		if mergedLine >= m.StartLineInMerged && mergedLine < m.StartLineInMerged+m.OriginalLineCount {
+			return m.File, mergedLine - m.StartLineInMerged + 1
+		}
+	}
+	return nil, 0
+}
+
+// MergeLayout returns a human readable report of the layout of the last
+// merged sketch source (see PrepareSketchBuildPath and MergedSketchSource):
+// the sketch files merged, in order, with the line of the merged source
+// where each one starts and its length, without the source itself. It may
+// help to triage the preprocessing issues. An empty string is returned if no
+// source has been merged yet.
+func (b *Builder) MergeLayout() string {
+	b.sketchMux.RLock()
+	defer b.sketchMux.RUnlock()
+	sourceMap := b.lastSourceMap()
+	if len(sourceMap) == 0 {
+		return ""
+	}
+	relPath := func(file *paths.Path) string {
+		if rel, err := b.sketch.FullPath.RelTo(file); err == nil {
+			return rel.String()
+		}
+		return file.String()
+	}
+
+	var res strings.Builder
+	res.WriteString(tr("Layout of the merged sketch source %s:", b.mergedFileName()) + "\n")
+	if generated := sourceMap[0].StartLineInMerged - 1; generated > 0 {
+		res.WriteString(fmt.Sprintf("  %-10s %s\n", tr("lines %[1]d-%[2]d", 1, generated), tr("added by the merge")))
+	}
+	for _, m := range sourceMap {
+		res.WriteString(fmt.Sprintf("  %-10s %s %s\n", tr("line %d", m.StartLineInMerged), relPath(m.File), tr("(%d lines)", m.OriginalLineCount)))
+	}
+	if len(b.MergeExcludedFiles) > 0 {
+		excluded := append([]string{}, b.MergeExcludedFiles...)
+		sort.Strings(excluded)
+		res.WriteString(tr("Excluded from the merge: %s", strings.Join(excluded, ", ")) + "\n")
+	}
+	return res.String()
+}
+
+// PreparationStats returns the statistics of the last PrepareSketchBuildPath
+// run, it can be used to report the progress of the sketch preparation.
+func (b *Builder) PreparationStats() SketchPreparationStats {
+	b.stateMux.Lock()
+	defer b.stateMux.Unlock()
+	return b.stats
+}
+
+// OverriddenFiles returns the paths, relative to the sketch folder, of the
+// files whose content has been taken from the source overrides instead of
+// the disk in the last PrepareSketchBuildPath run. It may help to understand
+// why a change to a sketch file didn't take effect.
+func (b *Builder) OverriddenFiles() []string {
+	b.stateMux.Lock()
+	defer b.stateMux.Unlock()
+	res := append([]string{}, b.overridden...)
+	sort.Strings(res)
+	return res
+}
+
+func (b *Builder) addOverridden(relpath *paths.Path) {
+	b.stateMux.Lock()
+	b.overridden = append(b.overridden, relpath.String())
+	b.stateMux.Unlock()
+}
+
+// setSourceMap records the placement of the sketch files in the last merged
+// source, see RemapDiagnostic
+func (b *Builder) setSourceMap(sourceMap []SketchSourceMapping) {
+	b.stateMux.Lock()
+	b.sourceMap = sourceMap
+	b.stateMux.Unlock()
+}
+
+func (b *Builder) lastSourceMap() []SketchSourceMapping {
+	b.stateMux.Lock()
+	defer b.stateMux.Unlock()
+	return b.sourceMap
+}
+
+func (b *Builder) addToStats(size int) {
+	b.stateMux.Lock()
+	b.stats.FilesCopied++
+	b.stats.BytesWritten += int64(size)
+	b.stateMux.Unlock()
+}
+
+// SourceIncludesArduinoH returns true if the given source code contains an
+// #include directive for Arduino.h. This is the same check used to decide
+// whether the Arduino.h inclusion must be added to the merged sketch.
+func SourceIncludesArduinoH(src string) bool {
+	return includesArduinoH.MatchString(src)
+}
+
+// mainSourceIncludesArduinoH works like SourceIncludesArduinoH, but the
+// result is reused if the source is the same of the previous call, to speed up
+// the repeated preparations of an unchanged sketch.
+func (b *Builder) mainSourceIncludesArduinoH(src string) bool {
+	hash := sha256.Sum256([]byte(src))
+	b.includesArduinoHMux.Lock()
+	defer b.includesArduinoHMux.Unlock()
+	if b.includesArduinoH.valid && b.includesArduinoH.hash == hash {
+		return b.includesArduinoH.result
+	}
+	result := SourceIncludesArduinoH(src)
+	b.includesArduinoH = includesArduinoHResult{valid: true, hash: hash, result: result}
+	return result
+}
+
 // SketchSaveItemCpp saves a preprocessed .cpp sketch file on disk
 func SketchSaveItemCpp(path *paths.Path, contents []byte, destPath *paths.Path) error {
 	sketchName := path.Base()
+	return saveCpp(destPath.Join(fmt.Sprintf("%s.cpp", sketchName)), contents, destPath)
+}
+
+// saveCpp saves the contents of a .cpp file in destFile, creating the
+// destPath folder if needed. The file is not touched if it already has the
+// given contents, to preserve its timestamp for incremental builds.
+func saveCpp(destFile *paths.Path, contents []byte, destPath *paths.Path) error {
 	if err := destPath.MkdirAll(); err != nil {
 		return errors.Wrap(err, tr("unable to create a folder to save the sketch"))
 	}
 
-	destFile := destPath.Join(fmt.Sprintf("%s.cpp", sketchName))
-
-	if err := destFile.WriteFile(contents); err != nil {
+	if err := writeIfDifferent(contents, destFile); err != nil {
 		return errors.Wrap(err, tr("unable to save the sketch on disk"))
 	}
 
 	return nil
 }
 
+// SketchFileError is returned when a file of the sketch can't be processed.
+// The offending File is kept apart from the Message, to let the callers find
+// it without parsing the error string.
+type SketchFileError struct {
+	File    *paths.Path
+	Message string
+	Cause   error
+}
+
+func (e *SketchFileError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Message, e.File, e.Cause)
+}
+
+func (e *SketchFileError) Unwrap() error {
+	return e.Cause
+}
+
 // sketchMergeSources merges all the .ino source files included in a sketch to produce
-// a single .cpp file.
-func sketchMergeSources(sk *sketch.Sketch, overrides map[string]string) (int, string, error) {
-	lineOffset := 0
-	mergedSource := ""
+// a single .cpp file. If the main file doesn't need to be merged (see
+// Builder.MainFileExtensions) its source is returned unchanged.
+// The main file is always placed first, followed by the other sketch files
+// sorted by path, so that the merged output doesn't depend on the order
+// in which the files have been enumerated. The files listed in
+// Builder.MergeExcludedFiles are skipped. Each file is preceded by a line
+// directive in the Builder.LineDirectiveStyle syntax. Arduino.h is included at
+// the top if the main file doesn't include it, unless Builder.NoPrelude is set,
+// followed by the Builder.Prologue lines; the Builder.Epilogue lines are added
+// at the end. The returned line offset counts the lines added before the
+// sketch code. The functions defined in more than one of the merged files are
+// reported (see Builder.ReportDuplicateFunction).
+func (b *Builder) sketchMergeSources(overrides map[string]string) (int, string, []SketchSourceMapping, error) {
+	var mergedSource strings.Builder
+	lineOffset, sourceMap, err := b.sketchMergeSourcesTo(&mergedSource, overrides)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	return lineOffset, mergedSource.String(), sourceMap, nil
+}
 
-	getSource := func(f *paths.Path) (string, error) {
-		path, err := sk.FullPath.RelTo(f)
+// sketchMergeSourcesTo works like sketchMergeSources, but the merged source is
+// written to w instead of being returned.
+func (b *Builder) sketchMergeSourcesTo(w io.Writer, overrides map[string]string) (int, []SketchSourceMapping, error) {
+	if !b.mainFileNeedsMerge() {
+		mainSrc, err := b.sketchFileSource(b.sketch.MainFile, overrides)
 		if err != nil {
-			return "", errors.Wrap(err, tr("unable to compute relative path to the sketch for the item"))
+			return 0, nil, err
+		}
+		return b.sketchMergeFilesTo(w, paths.PathList{b.sketch.MainFile}, []string{mainSrc})
+	}
+	files, sources, err := b.sketchFilesSources(overrides)
+	if err != nil {
+		return 0, nil, err
+	}
+	return b.sketchMergeFilesTo(w, files, sources)
+}
+
+// sketchMergeFilesTo writes to w the merge of the given .ino files and
+// sources, as returned by sketchFilesSources, see sketchMergeSources.
+func (b *Builder) sketchMergeFilesTo(w io.Writer, files paths.PathList, sources []string) (int, []SketchSourceMapping, error) {
+	sk := b.sketch
+	lineOffset := 0
+	mergedLines := 0
+	sourceMap := []SketchSourceMapping{}
+
+	// write keeps the first error, the following writes are skipped
+	var writeErr error
+	write := func(data string) {
+		if writeErr == nil {
+			_, writeErr = io.WriteString(w, data)
+		}
+	}
+
+	// appendSource adds the source of file to the merged source, preceded by
+	// a line directive, and records where it has been placed
+	appendSource := func(file *paths.Path, src string) {
+		write(b.LineDirectiveStyle.lineDirective(1, file))
+		write(src)
+		write("\n")
+		sourceMap = append(sourceMap, SketchSourceMapping{
+			File:              file,
+			StartLineInMerged: mergedLines + 2,
+			OriginalLineCount: countLines(src),
+		})
+		if b.OnSourceMerged != nil {
+			b.OnSourceMerged(file, mergedLines+2)
 		}
-		if override, ok := overrides[path.String()]; ok {
-			return override, nil
+		mergedLines += 1 + strings.Count(src, "\n") + 1
+	}
+
+	mainSrc := sources[0]
+	if !b.mainFileNeedsMerge() {
+		sourceMap = append(sourceMap, SketchSourceMapping{
+			File:              sk.MainFile,
+			StartLineInMerged: 1,
+			OriginalLineCount: countLines(mainSrc),
+		})
+		write(mainSrc)
+		if writeErr != nil {
+			return 0, nil, writeErr
 		}
-		data, err := f.ReadFile()
-		if err != nil {
-			return "", fmt.Errorf(tr("reading file %[1]s: %[2]s"), f, err)
+		if b.OnSourceMerged != nil {
+			b.OnSourceMerged(sk.MainFile, 1)
 		}
-		return string(data), nil
+		b.setSourceMap(sourceMap)
+		return 0, sourceMap, nil
 	}
 
-	// add Arduino.h inclusion directive if missing
-	mainSrc, err := getSource(sk.MainFile)
-	if err != nil {
-		return 0, "", err
+	// add Arduino.h inclusion directive if missing, unless disabled
+	if !b.NoPrelude && !b.mainSourceIncludesArduinoH(mainSrc) {
+		write("#include <Arduino.h>\n")
+		mergedLines++
+		lineOffset++
+	}
+	for _, line := range b.Prologue {
+		write(line + "\n")
+		added := strings.Count(line, "\n") + 1
+		mergedLines += added
+		lineOffset += added
+	}
+
+	// report duplicated functions before they become obscure linker errors
+	if err := checkDuplicateFunctions(files, sources); err != nil {
+		if b.StrictDuplicateFunctions {
+			return 0, nil, err
+		}
+		if b.ReportDuplicateFunction != nil {
+			b.ReportDuplicateFunction(err)
+		}
 	}
-	if !includesArduinoH.MatchString(mainSrc) {
-		mergedSource += "#include <Arduino.h>\n"
+	if b.ReportMergeHazard != nil {
+		for _, hazard := range findMergeHazards(files, sources) {
+			b.ReportMergeHazard(hazard)
+		}
+	}
+
+	prototypes := []string{}
+	if b.SplitTranslationUnits {
+		// the other files are saved as separate translation units, see
+		// sketchSaveTranslationUnits
+		prototypes = unitPrototypes(0, files, sources)
+	}
+	if b.GeneratePrototypes {
+		mergedSources := sources
+		if b.SplitTranslationUnits {
+			mergedSources = sources[:1]
+		}
+		for _, prototype := range sketchPrototypes(mergedSources) {
+			if !slices.Contains(prototypes, prototype) {
+				prototypes = append(prototypes, prototype)
+			}
+		}
+	}
+	for _, prototype := range prototypes {
+		write(prototype + "\n")
+		mergedLines++
 		lineOffset++
 	}
 
-	mergedSource += "#line 1 " + QuoteCppString(sk.MainFile.String()) + "\n"
-	mergedSource += mainSrc + "\n"
+	if b.SplitTranslationUnits {
+		appendSource(files[0], sources[0])
+	} else {
+		for i, file := range files {
+			appendSource(file, sources[i])
+		}
+	}
 	lineOffset++
+	for _, line := range b.Epilogue {
+		write(line + "\n")
+	}
+	if writeErr != nil {
+		return 0, nil, writeErr
+	}
 
-	for _, file := range sk.OtherSketchFiles {
-		src, err := getSource(file)
+	b.setSourceMap(sourceMap)
+	return lineOffset, sourceMap, nil
+}
+
+// utf8BOM is the byte order mark that some editors add at the beginning of
+// UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// stripUTF8BOM removes the UTF-8 byte order mark from the beginning of data
+func stripUTF8BOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, utf8BOM)
+}
+
+// countLines returns the number of lines contained in src
+func countLines(src string) int {
+	lines := strings.Count(src, "\n")
+	if src != "" && !strings.HasSuffix(src, "\n") {
+		lines++
+	}
+	return lines
+}
+
+// overrideKey returns the key of the source overrides for the sketch file at
+// relpath: the overrides, and the other options keyed by sketch file, are
+// looked up with forward slashes as separators, whatever the OS, so that
+// they match even if they have been set with the paths of a different OS
+// (see normalizeOverrides).
+func overrideKey(relpath string) string {
+	return path.Clean(strings.ReplaceAll(relpath, "\\", "/"))
+}
+
+// normalizeOverrides returns a copy of overrides with the keys converted to
+// the form used by overrideKey, so that the OS-native and the forward-slash
+// paths may be used interchangeably. If both forms of the same path are
+// set, the forward-slash one wins.
+func normalizeOverrides(overrides map[string]string) map[string]string {
+	if overrides == nil {
+		return nil
+	}
+	res := make(map[string]string, len(overrides))
+	for file, src := range overrides {
+		key := overrideKey(file)
+		if _, canonicalSet := overrides[key]; canonicalSet && key != file {
+			continue
+		}
+		res[key] = src
+	}
+	return res
+}
+
+// sketchFileSource returns the source of a sketch file, taken from the
+// overrides if available.
+func (b *Builder) sketchFileSource(file *paths.Path, overrides map[string]string) (string, error) {
+	relpath, err := b.sketch.FullPath.RelTo(file)
+	if err != nil {
+		return "", &SketchFileError{File: file, Message: tr("unable to compute relative path to the sketch for the item"), Cause: err}
+	}
+	var data []byte
+	if override, ok := overrides[overrideKey(relpath.String())]; ok {
+		data = []byte(override)
+	} else if data, err = b.readFile(file); err != nil {
+		return "", &SketchFileError{File: file, Message: tr("reading file"), Cause: err}
+	}
+	data, err = b.SourceEncoding.toUTF8(stripUTF8BOM(data))
+	if err != nil {
+		return "", &SketchFileError{File: file, Message: tr("converting the file to UTF-8"), Cause: err}
+	}
+	return string(data), nil
+}
+
+// sketchFilesSources returns the .ino files of the sketch, in merge order
+// (see sketchMergeSources), together with their sources.
+func (b *Builder) sketchFilesSources(overrides map[string]string) (paths.PathList, []string, error) {
+	sk := b.sketch
+	mainSrc, err := b.sketchFileSource(sk.MainFile, overrides)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := paths.PathList{sk.MainFile}
+	sources := []string{mainSrc}
+	otherFiles := sk.OtherSketchFiles.Clone()
+	otherFiles.Sort()
+	for _, file := range otherFiles {
+		if relpath, err := sk.FullPath.RelTo(file); err == nil && b.isMergeExcluded(relpath) {
+			continue
+		}
+		src, err := b.sketchFileSource(file, overrides)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, file)
+		sources = append(sources, src)
+	}
+	return files, sources, nil
+}
+
+// sketchSaveTranslationUnits saves each of the given .ino files, as returned
+// by sketchFilesSources, apart from the main one that is saved by the merge,
+// as a separate .cpp file in destPath (see Builder.SplitTranslationUnits).
+func (b *Builder) sketchSaveTranslationUnits(destPath *paths.Path, overrides map[string]string, files paths.PathList, sources []string) error {
+	for i := 1; i < len(files); i++ {
+		relpath, err := b.sketch.FullPath.RelTo(files[i])
+		if err != nil {
+			return &SketchFileError{File: files[i], Message: tr("unable to compute relative path to the sketch for the item"), Cause: err}
+		}
+		if _, ok := overrides[overrideKey(relpath.String())]; ok {
+			b.addOverridden(relpath)
+		}
+
+		var unit strings.Builder
+		if !b.NoPrelude && !SourceIncludesArduinoH(sources[i]) {
+			unit.WriteString("#include <Arduino.h>\n")
+		}
+		for _, prototype := range unitPrototypes(i, files, sources) {
+			unit.WriteString(prototype + "\n")
+		}
+		unit.WriteString(b.LineDirectiveStyle.lineDirective(1, files[i]))
+		unit.WriteString(sources[i])
+		unit.WriteString("\n")
+
+		if err := saveCpp(destPath.JoinPath(relpath).Parent().Join(relpath.Base()+".cpp"), []byte(unit.String()), destPath); err != nil {
+			return err
+		}
+		b.addToStats(unit.Len())
+	}
+	return nil
+}
+
+// unitPrototypes returns the prototypes to add to the translation unit of
+// files[unit]: the prototypes of the functions called in the unit and
+// defined in the other .ino files and, if the unit is not the main one that
+// goes through the sketch preprocessor, the prototypes of the functions
+// defined in the unit itself (see sketchPrototypes), so that they may be
+// used before their definition. The functions are found with the same
+// heuristic used by checkDuplicateFunctions.
+func unitPrototypes(unit int, files paths.PathList, sources []string) []string {
+	res := []string{}
+	if unit > 0 {
+		res = append(res, sketchPrototypes(sources[unit:unit+1])...)
+	}
+	called := map[string]bool{}
+	for _, m := range functionCall.FindAllStringSubmatch(removeCommentsAndLiterals(sources[unit]), -1) {
+		called[m[1]] = true
+	}
+	declared := map[string]bool{}
+	for i := range files {
+		if i == unit {
+			continue
+		}
+		for _, f := range findFunctionDefinitions(sources[i]) {
+			if strings.Contains(f.name, "::") || declared[f.signature] || !called[f.name] {
+				continue
+			}
+			res = append(res, f.prototype)
+			declared[f.signature] = true
+		}
+	}
+	return res
+}
+
+// sketchCopySketchFiles copies the main file and the other sketch files, each
+// one tagged with a #line directive, to the specified destination directory
+// without merging them. The files listed in Builder.MergeExcludedFiles are
+// skipped.
+func (b *Builder) sketchCopySketchFiles(destPath *paths.Path, overrides map[string]string) error {
+	sk := b.sketch
+	if err := destPath.MkdirAll(); err != nil {
+		return errors.Wrap(err, tr("unable to create a folder to save the sketch files"))
+	}
+	files := append(paths.PathList{sk.MainFile}, sk.OtherSketchFiles...)
+	for _, file := range files {
+		relpath, err := sk.FullPath.RelTo(file)
 		if err != nil {
-			return 0, "", err
+			return &SketchFileError{File: file, Message: tr("unable to compute relative path to the sketch for the item"), Cause: err}
+		}
+		if b.isMergeExcluded(relpath) {
+			continue
+		}
+		override, overridden := overrides[overrideKey(relpath.String())]
+		if overridden {
+			b.addOverridden(relpath)
+		}
+		if err := b.copySketchFile(file, destPath.JoinPath(relpath), true, 1, override, overridden); err != nil {
+			return err
 		}
-		mergedSource += "#line 1 " + QuoteCppString(file.String()) + "\n"
-		mergedSource += src + "\n"
 	}
+	return nil
+}
 
-	return lineOffset, mergedSource, nil
+// sketchCopyMainFile copies the main file of a sketch that doesn't need
+// preprocessing in destPath, as it is, and returns its source.
+func (b *Builder) sketchCopyMainFile(destPath *paths.Path, overrides map[string]string) (string, []SketchSourceMapping, error) {
+	mainFile := b.sketch.MainFile
+	relpath, err := b.sketch.FullPath.RelTo(mainFile)
+	if err != nil {
+		return "", nil, &SketchFileError{File: mainFile, Message: tr("unable to compute relative path to the sketch for the item"), Cause: err}
+	}
+	src, err := b.sketchFileSource(mainFile, overrides)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, ok := overrides[overrideKey(relpath.String())]; ok {
+		b.addOverridden(relpath)
+	}
+	sourceMap := []SketchSourceMapping{{
+		File:              mainFile,
+		StartLineInMerged: 1,
+		OriginalLineCount: countLines(src),
+	}}
+	if b.OnSourceMerged != nil {
+		b.OnSourceMerged(mainFile, 1)
+	}
+	b.setSourceMap(sourceMap)
+	if err := saveCpp(destPath.Join(b.mergedFileName()), []byte(src), destPath); err != nil {
+		return "", nil, err
+	}
+	b.addToStats(len(src))
+	return src, sourceMap, nil
 }
 
 // sketchCopyAdditionalFiles copies the additional files for a sketch to the
-// specified destination directory.
-func sketchCopyAdditionalFiles(sketch *sketch.Sketch, destPath *paths.Path, overrides map[string]string) error {
+// specified destination directory. Up to Builder.Jobs files are copied in
+// parallel; if some of the copies fail the first error is returned.
+func (b *Builder) sketchCopyAdditionalFiles(destPath *paths.Path, overrides map[string]string) error {
+	sketch := b.sketch
 	if err := destPath.MkdirAll(); err != nil {
 		return errors.Wrap(err, tr("unable to create a folder to save the sketch files"))
 	}
 
+	var errorsList []error
+	var errorsMux sync.Mutex
+
+	queue := make(chan *paths.Path)
+	job := func(file *paths.Path) {
+		if err := b.sketchCopyAdditionalFile(file, destPath, overrides); err != nil {
+			errorsMux.Lock()
+			errorsList = append(errorsList, err)
+			errorsMux.Unlock()
+		}
+	}
+
+	// Spawn jobs runners
+	var wg sync.WaitGroup
+	jobs := b.Jobs
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			for file := range queue {
+				job(file)
+			}
+			wg.Done()
+		}()
+	}
+
+	// Feed jobs until error or done
 	for _, file := range sketch.AdditionalFiles {
-		relpath, err := sketch.FullPath.RelTo(file)
+		errorsMux.Lock()
+		gotError := len(errorsList) > 0
+		errorsMux.Unlock()
+		if gotError {
+			break
+		}
+		queue <- file
+	}
+	close(queue)
+	wg.Wait()
+	if len(errorsList) > 0 {
+		// output the first error
+		return errorsList[0]
+	}
+	return nil
+}
+
+// sketchCopyAdditionalFile copies a single additional file of the sketch to
+// the specified destination directory.
+func (b *Builder) sketchCopyAdditionalFile(file *paths.Path, destPath *paths.Path, overrides map[string]string) error {
+	relpath, err := b.sketch.FullPath.RelTo(file)
+	if err != nil {
+		return &SketchFileError{File: file, Message: tr("unable to compute relative path to the sketch for the item"), Cause: err}
+	}
+
+	targetPath := destPath.JoinPath(relpath)
+	// create the directory containing the target
+	if err = targetPath.Parent().MkdirAll(); err != nil {
+		return longPathError(runtime.GOOS, file, targetPath, errors.Wrap(err, tr("unable to create the folder containing the item")))
+	}
+
+	override, overridden := overrides[overrideKey(relpath.String())]
+	if overridden {
+		b.addOverridden(relpath)
+	}
+	if !overridden && b.AdditionalFilesMode != AdditionalFilesCopy && b.FileReader == nil && !isCppSourceFile(file) {
+		err := linkAdditionalFile(b.AdditionalFilesMode, file, targetPath)
+		if err == nil {
+			b.addToStats(0)
+			return nil
+		}
+		logrus.Debugf("Could not link %s, falling back to copy: %s", file, err)
+	}
+
+	// only the sources are tagged, the other files (like data assets) are
+	// copied verbatim
+	startLine := b.additionalFileStartLine(relpath)
+	if err := b.copySketchFile(file, targetPath, isCppSourceFile(file), startLine, override, overridden); err != nil {
+		return longPathError(runtime.GOOS, file, targetPath, err)
+	}
+	return nil
+}
+
+// windowsMaxPath is the MAX_PATH limit of the Windows API, many tools (for
+// example the compilers) can't use longer paths
+const windowsMaxPath = 260
+
+// longPathError returns an error explaining that the build path must be
+// shortened if, on Windows (goos), the failure err while copying file to
+// targetPath may be caused by targetPath exceeding MAX_PATH. Otherwise err is
+// returned unchanged.
+func longPathError(goos string, file, targetPath *paths.Path, err error) error {
+	if goos != "windows" {
+		return err
+	}
+	if absPath, absErr := targetPath.Abs(); absErr == nil {
+		targetPath = absPath
+	}
+	if len(targetPath.String()) < windowsMaxPath {
+		return err
+	}
+	return &SketchFileError{
+		File: file,
+		Message: tr("the destination path %[1]s is %[2]d characters long, over the %[3]d characters limit of Windows: use a shorter build path (or move the sketch to a shorter path) to copy",
+			targetPath, len(targetPath.String()), windowsMaxPath),
+		Cause: err,
+	}
+}
+
+// copySketchFile writes the content of file, or its override if overridden is
+// true, to targetPath. If tag is true the content is preceded by a #line
+// directive stating that it starts at line startLine of file, unless the
+// content looks binary.
+func (b *Builder) copySketchFile(file, targetPath *paths.Path, tag bool, startLine int, override string, overridden bool) error {
+	// never write through a link, it would change the original sketch file
+	if err := removeIfLinkedTo(targetPath, file); err != nil {
+		return errors.Wrap(err, tr("unable to write to destination file"))
+	}
+
+	var sourceBytes []byte
+	if overridden {
+		// use override source
+		sourceBytes = []byte(override)
+	} else {
+		// read the source file
+		s, err := b.readFile(file)
 		if err != nil {
-			return errors.Wrap(err, tr("unable to compute relative path to the sketch for the item"))
+			return errors.Wrap(err, tr("unable to read contents of the source item"))
 		}
+		sourceBytes = s
+	}
+
+	if tag && !looksBinary(sourceBytes) {
+		// tag each addtional file with the filename of the source it was copied from,
+		// a BOM would end up after the tag and break the compile
+		sourceBytes = stripUTF8BOM(sourceBytes)
+		sourceBytes = append([]byte("#line "+strconv.Itoa(startLine)+" "+QuoteCppString(file.String())+"\n"), sourceBytes...)
+	}
+
+	if err := writeIfDifferent(sourceBytes, targetPath); err != nil {
+		return errors.Wrap(err, tr("unable to write to destination file"))
+	}
+	// keep the permissions of the original file, for example the executable bit
+	if b.FileReader == nil {
+		if err := copyPermissions(file, targetPath); err != nil {
+			return errors.Wrap(err, tr("unable to set the permissions of the destination file"))
+		}
+	}
+	b.addToStats(len(sourceBytes))
+	return nil
+}
+
+// readFile reads the content of a sketch file through the b.FileReader, or
+// from disk if it's not set.
+func (b *Builder) readFile(file *paths.Path) ([]byte, error) {
+	if b.FileReader != nil {
+		return b.FileReader.ReadFile(file)
+	}
+	return file.ReadFile()
+}
+
+// looksBinary returns true if data doesn't look like text, that is if it
+// contains a NUL byte in its first 8000 bytes (the same heuristic used by git).
+func looksBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
+
+// copyPermissions sets the permission bits of target to the ones of source,
+// if they differ. The target is kept writable by its owner, so that it can be
+// updated by the following builds.
+func copyPermissions(source, target *paths.Path) error {
+	sourceInfo, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	targetInfo, err := target.Stat()
+	if err != nil {
+		return err
+	}
+	perm := sourceInfo.Mode().Perm() | 0200
+	if perm == targetInfo.Mode().Perm() {
+		return nil
+	}
+	return os.Chmod(target.String(), perm)
+}
+
+// removeStaleAdditionalFiles removes from destPath the copies of the
+// additional files that are not part of the sketch anymore, together with
+// the object files compiled from them.
+func (b *Builder) removeStaleAdditionalFiles(destPath *paths.Path) error {
+	if destPath.NotExist() {
+		return nil
+	}
+	files, err := destPath.ReadDirRecursive()
+	if err != nil {
+		return errors.Wrap(err, tr("unable to read the content of the build path"))
+	}
 
-		targetPath := destPath.JoinPath(relpath)
-		// create the directory containing the target
-		if err = targetPath.Parent().MkdirAll(); err != nil {
-			return errors.Wrap(err, tr("unable to create the folder containing the item"))
+	planned, err := b.plannedSketchFiles(destPath)
+	if err != nil {
+		return err
+	}
+	current := map[string]bool{}
+	if !b.NoMerge {
+		// the merged sketch source saved with the default name
+		current[b.sketch.MainFile.Base()+".cpp"] = true
+	}
+	for _, file := range planned {
+		if relpath, err := destPath.RelTo(file); err == nil {
+			current[relpath.String()] = true
 		}
+	}
 
-		var sourceBytes []byte
-		if override, ok := overrides[relpath.String()]; ok {
-			// use override source
-			sourceBytes = []byte(override)
-		} else {
-			// read the source file
-			s, err := file.ReadFile()
-			if err != nil {
-				return errors.Wrap(err, tr("unable to read contents of the source item"))
+	for _, file := range files {
+		if _, ok := globals.AdditionalFileValidExtensions[file.Ext()]; !ok || file.IsDir() {
+			continue
+		}
+		relpath, err := destPath.RelTo(file)
+		if err != nil || current[relpath.String()] {
+			continue
+		}
+		logrus.Debugf("Removing stale sketch file from build path: %s", file)
+		for _, stale := range []*paths.Path{file, paths.New(file.String() + ".o"), paths.New(file.String() + ".d")} {
+			if err := stale.Remove(); err != nil && !os.IsNotExist(err) {
+				return errors.Wrap(err, tr("unable to remove stale file %s", stale))
 			}
-			sourceBytes = s
 		}
+	}
+	return nil
+}
 
-		// tag each addtional file with the filename of the source it was copied from
-		sourceBytes = append([]byte("#line 1 "+QuoteCppString(file.String())+"\n"), sourceBytes...)
+// isCppSourceFile returns true if file is a C/C++ source or header that must
+// be tagged with a #line directive when placed in the build path.
+func isCppSourceFile(file *paths.Path) bool {
+	ext := file.Ext()
+	if _, ok := globals.SourceFilesValidExtensions[ext]; ok {
+		return true
+	}
+	if _, ok := globals.HeaderFilesValidExtensions[ext]; ok {
+		return true
+	}
+	// template implementation files are included like headers
+	return ext == ".tpp" || ext == ".ipp"
+}
 
-		err = writeIfDifferent(sourceBytes, targetPath)
+// linkAdditionalFile creates a link to file in targetPath using the given
+// mode. Nothing is done if targetPath is already linked to file.
+func linkAdditionalFile(mode AdditionalFilesMode, file, targetPath *paths.Path) error {
+	absFile, err := file.Abs()
+	if err != nil {
+		return err
+	}
+	if isLinkedTo(targetPath, absFile) {
+		return nil
+	}
+	if err := targetPath.Remove(); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	switch mode {
+	case AdditionalFilesHardLink:
+		return os.Link(absFile.String(), targetPath.String())
+	case AdditionalFilesSymlink:
+		return os.Symlink(absFile.String(), targetPath.String())
+	}
+	return fmt.Errorf("invalid additional files mode: %d", mode)
+}
+
+// isLinkedTo returns true if targetPath is a symbolic link or a hard link
+// to file.
+func isLinkedTo(targetPath, file *paths.Path) bool {
+	targetInfo, err := os.Lstat(targetPath.String())
+	if err != nil {
+		return false
+	}
+	if targetInfo.Mode()&os.ModeSymlink != 0 {
+		dest, err := os.Readlink(targetPath.String())
 		if err != nil {
-			return errors.Wrap(err, tr("unable to write to destination file"))
+			return false
 		}
+		absFile, err := file.Abs()
+		return err == nil && dest == absFile.String()
 	}
+	fileInfo, err := os.Stat(file.String())
+	return err == nil && os.SameFile(targetInfo, fileInfo)
+}
 
+// removeIfLinkedTo removes targetPath if it's a symbolic link, or a hard link
+// to file, for example left by a previous build.
+func removeIfLinkedTo(targetPath, file *paths.Path) error {
+	if targetInfo, err := os.Lstat(targetPath.String()); err == nil && targetInfo.Mode()&os.ModeSymlink != 0 {
+		return targetPath.Remove()
+	}
+	if isLinkedTo(targetPath, file) {
+		return targetPath.Remove()
+	}
 	return nil
 }
 
@@ -163,14 +1165,15 @@ func writeIfDifferent(source []byte, destPath *paths.Path) error {
 		return destPath.WriteFile(source)
 	}
 
-	// Read the destination file if it exists
-	existingBytes, err := destPath.ReadFile()
+	// Compare the destination file with the source, without reading it
+	// entirely in memory
+	equal, err := fileContentEquals(destPath, source)
 	if err != nil {
 		return errors.Wrap(err, tr("unable to read contents of the destination item"))
 	}
 
 	// Overwrite if contents are different
-	if !bytes.Equal(existingBytes, source) {
+	if !equal {
 		return destPath.WriteFile(source)
 	}
 
@@ -178,6 +1181,41 @@ func writeIfDifferent(source []byte, destPath *paths.Path) error {
 	return nil
 }
 
+// fileContentEquals returns true if the content of file is equal to data.
+// The file sizes are compared first, then the file is read in chunks and the
+// comparison stops at the first difference.
+func fileContentEquals(file *paths.Path, data []byte) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() != int64(len(data)) {
+		return false, nil
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 64*1024)
+	for offset := 0; offset < len(data); {
+		n, err := f.Read(buf)
+		if n > len(data)-offset || !bytes.Equal(buf[:n], data[offset:offset+n]) {
+			return false, nil
+		}
+		offset += n
+		if err == io.EOF {
+			// the file has been truncated while reading
+			return offset == len(data), nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
 // SetupBuildProperties adds the build properties related to the sketch to the
 // default board build properties map.
 func SetupBuildProperties(boardBuildProperties *properties.Map, buildPath *paths.Path, sketch *sketch.Sketch, optimizeForDebug bool) *properties.Map {
@@ -16,12 +16,16 @@
 package builder
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/arduino/arduino-cli/arduino/sketch"
 	"github.com/arduino/go-paths-helper"
@@ -81,23 +85,407 @@ func TestMergeSketchSources(t *testing.T) {
 	}
 	mergedSources := strings.ReplaceAll(string(mergedBytes), "%s", pathToGoldenSource)
 
-	offset, source, err := sketchMergeSources(s, nil)
+	offset, source, _, err := NewBuilder(s).sketchMergeSources(nil)
 	require.Nil(t, err)
 	require.Equal(t, 2, offset)
 	require.Equal(t, mergedSources, source)
 }
 
+func TestMergeSketchSourcesFileError(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+	missing := s.FullPath.Join("missing.ino")
+	s.OtherSketchFiles.Add(missing)
+
+	_, _, _, err = NewBuilder(s).sketchMergeSources(nil)
+	var fileErr *SketchFileError
+	require.ErrorAs(t, err, &fileErr)
+	require.Equal(t, missing, fileErr.File)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestMergeSketchSourcesLineDirectiveStyle(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+
+	gccOffset, gccSource, gccSourceMap, err := NewBuilder(s).sketchMergeSources(nil)
+	require.Nil(t, err)
+
+	b := NewBuilder(s)
+	b.LineDirectiveStyle = LineDirectiveGNUMarker
+	offset, source, sourceMap, err := b.sketchMergeSources(nil)
+	require.Nil(t, err)
+	require.Equal(t, gccOffset, offset)
+	require.Equal(t, gccSourceMap, sourceMap)
+	require.NotContains(t, source, "#line ")
+	require.Contains(t, source, "# 1 "+QuoteCppString(s.MainFile.String())+"\n")
+
+	// the two styles differ only in the directive keyword
+	require.Equal(t, gccSource, strings.ReplaceAll(source, "\n# 1 ", "\n#line 1 "))
+}
+
+func TestMergeSketchSourcesOrderIsStable(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+	require.True(t, len(s.OtherSketchFiles) > 1)
+
+	_, expected, _, err := NewBuilder(s).sketchMergeSources(nil)
+	require.Nil(t, err)
+
+	// reverse the order of the other sketch files
+	reversed := paths.PathList{}
+	for i := len(s.OtherSketchFiles) - 1; i >= 0; i-- {
+		reversed.Add(s.OtherSketchFiles[i])
+	}
+	s.OtherSketchFiles = reversed
+
+	_, source, _, err := NewBuilder(s).sketchMergeSources(nil)
+	require.Nil(t, err)
+	require.Equal(t, expected, source)
+}
+
+func TestMergeSketchSourcesExcludedFiles(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+
+	b := NewBuilder(s)
+	b.MergeExcludedFiles = []string{"other.ino"}
+	offset, source, sourceMap, err := b.sketchMergeSources(nil)
+	require.Nil(t, err)
+	require.Equal(t, 2, offset)
+	require.NotContains(t, source, "other.ino")
+	require.Contains(t, source, "old.pde")
+	require.Len(t, sourceMap, len(s.OtherSketchFiles))
+	for _, mapping := range sourceMap {
+		require.NotEqual(t, "other.ino", mapping.File.Base())
+	}
+
+	// excluded files are left on disk
+	require.True(t, s.FullPath.Join("other.ino").Exist())
+
+	// the paths may use either separator
+	for _, excluded := range []string{"./other.ino", "src/../other.ino", "src\\..\\other.ino"} {
+		b.MergeExcludedFiles = []string{excluded}
+		_, source, _, err = b.sketchMergeSources(nil)
+		require.NoError(t, err)
+		require.NotContains(t, source, "other.ino", excluded)
+	}
+}
+
+func TestMergeSketchSourcesSourceMap(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+	require.NotNil(t, s)
+
+	_, source, sourceMap, err := NewBuilder(s).sketchMergeSources(nil)
+	require.Nil(t, err)
+	require.Len(t, sourceMap, 3)
+
+	require.Equal(t, s.MainFile, sourceMap[0].File)
+	require.Equal(t, 3, sourceMap[0].StartLineInMerged)
+	require.Equal(t, 7, sourceMap[0].OriginalLineCount)
+	require.Equal(t, "old.pde", sourceMap[1].File.Base())
+	require.Equal(t, 11, sourceMap[1].StartLineInMerged)
+	require.Equal(t, 0, sourceMap[1].OriginalLineCount)
+	require.Equal(t, "other.ino", sourceMap[2].File.Base())
+	require.Equal(t, 13, sourceMap[2].StartLineInMerged)
+	require.Equal(t, 3, sourceMap[2].OriginalLineCount)
+
+	// each mapping must be preceded by the #line directive of its file
+	lines := strings.Split(source, "\n")
+	for _, m := range sourceMap {
+		require.Equal(t, "#line 1 "+QuoteCppString(m.File.String()), lines[m.StartLineInMerged-2])
+	}
+}
+
+func TestMergeSketchSourcesOnSourceMerged(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+	require.NotNil(t, s)
+
+	merged := []SketchSourceMapping{}
+	b := NewBuilder(s)
+	b.OnSourceMerged = func(file *paths.Path, startLine int) {
+		merged = append(merged, SketchSourceMapping{File: file, StartLineInMerged: startLine})
+	}
+	_, _, sourceMap, err := b.sketchMergeSources(nil)
+	require.Nil(t, err)
+	require.Len(t, merged, len(sourceMap))
+	for i, m := range sourceMap {
+		require.Equal(t, m.File, merged[i].File)
+		require.Equal(t, m.StartLineInMerged, merged[i].StartLineInMerged)
+	}
+}
+
+func TestMergeSketchSourcesEncoding(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+
+	overrides := map[string]string{
+		"other.ino": "// caf\xe9 \x80\n",
+		"old.pde":   "// caf\xc3\xa9\n",
+	}
+	b := NewBuilder(s)
+	_, source, _, err := b.sketchMergeSources(overrides)
+	require.NoError(t, err)
+	require.Contains(t, source, "// caf\xe9 \x80\n")
+	require.Contains(t, source, "// café\n")
+
+	b.SourceEncoding = SourceEncodingLatin1
+	_, source, _, err = b.sketchMergeSources(overrides)
+	require.NoError(t, err)
+	require.Contains(t, source, "// café \u0080\n")
+	require.Contains(t, source, "// café\n")
+
+	b.SourceEncoding = SourceEncodingWindows1252
+	_, source, _, err = b.sketchMergeSources(overrides)
+	require.NoError(t, err)
+	require.Contains(t, source, "// café €\n")
+	require.Contains(t, source, "// café\n")
+}
+
+func TestNormalizeOverrides(t *testing.T) {
+	require.Nil(t, normalizeOverrides(nil))
+	require.Equal(t,
+		map[string]string{"src/helper.h": "a", "other.ino": "b", "src/sub/x.cpp": "c"},
+		normalizeOverrides(map[string]string{"src\\helper.h": "a", "./other.ino": "b", "src/sub\\x.cpp": "c"}))
+
+	// the forward-slash key wins if both forms are set
+	require.Equal(t,
+		map[string]string{"src/helper.h": "fwd"},
+		normalizeOverrides(map[string]string{"src\\helper.h": "native", "src/helper.h": "fwd"}))
+}
+
+func TestPrepareSketchBuildPathOverridesSeparators(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+
+	for _, key := range []string{"src/helper.h", "src\\helper.h"} {
+		tmp := tmpDirOrDie()
+		defer tmp.RemoveAll()
+
+		b := NewBuilder(s)
+		overrides := map[string]string{key: "// overridden\n"}
+		_, _, err := b.PrepareSketchBuildPath(overrides, tmp)
+		require.NoError(t, err)
+		copied, err := tmp.Join("src", "helper.h").ReadFile()
+		require.NoError(t, err)
+		require.Contains(t, string(copied), "// overridden\n")
+	}
+}
+
+func TestMergeLayout(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+
+	b := NewBuilder(s)
+	require.Empty(t, b.MergeLayout())
+
+	_, _, err = b.MergedSketchSource(nil)
+	require.NoError(t, err)
+	require.Equal(t,
+		"Layout of the merged sketch source TestLoadSketchFolder.ino.cpp:\n"+
+			"  lines 1-2  added by the merge\n"+
+			"  line 3     TestLoadSketchFolder.ino (7 lines)\n"+
+			"  line 11    old.pde (0 lines)\n"+
+			"  line 13    other.ino (3 lines)\n",
+		b.MergeLayout())
+
+	b.MergeExcludedFiles = []string{"old.pde"}
+	_, _, err = b.MergedSketchSource(nil)
+	require.NoError(t, err)
+	require.Equal(t,
+		"Layout of the merged sketch source TestLoadSketchFolder.ino.cpp:\n"+
+			"  lines 1-2  added by the merge\n"+
+			"  line 3     TestLoadSketchFolder.ino (7 lines)\n"+
+			"  line 11    other.ino (3 lines)\n"+
+			"Excluded from the merge: old.pde\n",
+		b.MergeLayout())
+}
+
+func TestRemapDiagnostic(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+
+	b := NewBuilder(s)
+	file, line := b.RemapDiagnostic(3)
+	require.Nil(t, file)
+	require.Equal(t, 0, line)
+
+	_, _, err = b.MergedSketchSource(nil)
+	require.NoError(t, err)
+
+	// lines added by the merge don't belong to any file
+	for _, mergedLine := range []int{0, 1, 2, 10, 11, 12, 16, 100} {
+		file, _ := b.RemapDiagnostic(mergedLine)
+		require.Nil(t, file, "line %d", mergedLine)
+	}
+
+	file, line = b.RemapDiagnostic(3)
+	require.Equal(t, s.MainFile, file)
+	require.Equal(t, 1, line)
+	file, line = b.RemapDiagnostic(9)
+	require.Equal(t, s.MainFile, file)
+	require.Equal(t, 7, line)
+	file, line = b.RemapDiagnostic(14)
+	require.Equal(t, "other.ino", file.Base())
+	require.Equal(t, 2, line)
+}
+
 func TestMergeSketchSourcesArduinoIncluded(t *testing.T) {
 	s, err := sketch.New(paths.New("testdata", t.Name()))
 	require.Nil(t, err)
 	require.NotNil(t, s)
 
 	// ensure not to include Arduino.h when it's already there
-	_, source, err := sketchMergeSources(s, nil)
+	_, source, _, err := NewBuilder(s).sketchMergeSources(nil)
 	require.Nil(t, err)
 	require.Equal(t, 1, strings.Count(source, "<Arduino.h>"))
 }
 
+func TestMainSourceIncludesArduinoHCache(t *testing.T) {
+	b := NewBuilder(nil)
+	src := "void setup() {}\n"
+	require.False(t, b.mainSourceIncludesArduinoH(src))
+	require.True(t, b.includesArduinoH.valid)
+
+	// the result is reused while the source is unchanged
+	b.includesArduinoH.result = true
+	require.True(t, b.mainSourceIncludesArduinoH(src))
+
+	// and computed again when it changes
+	require.True(t, b.mainSourceIncludesArduinoH("#include <Arduino.h>\n"+src))
+	require.False(t, b.mainSourceIncludesArduinoH("// no includes\n"+src))
+}
+
+func TestMergeSketchSourcesNoPrelude(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+	offset, withPrelude, _, err := NewBuilder(s).sketchMergeSources(nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, offset)
+	require.True(t, strings.HasPrefix(withPrelude, "#include <Arduino.h>\n"))
+
+	b := NewBuilder(s)
+	b.NoPrelude = true
+	offset, source, _, err := b.sketchMergeSources(nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, offset)
+	require.Equal(t, strings.TrimPrefix(withPrelude, "#include <Arduino.h>\n"), source)
+
+	// the option defaults to the no_prelude key of the sketch project file
+	sketchPath := paths.New(t.TempDir(), "NoPrelude")
+	require.NoError(t, sketchPath.MkdirAll())
+	require.NoError(t, sketchPath.Join("NoPrelude.ino").WriteFile([]byte("int main() {}\n")))
+	require.NoError(t, sketchPath.Join("sketch.yaml").WriteFile([]byte("no_prelude: true\n")))
+	s, err = sketch.New(sketchPath)
+	require.NoError(t, err)
+	require.True(t, NewBuilder(s).NoPrelude)
+	_, source, _, err = NewBuilder(s).sketchMergeSources(nil)
+	require.NoError(t, err)
+	require.NotContains(t, source, "Arduino.h")
+}
+
+func TestMergeSketchSourcesGeneratePrototypes(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	mainFile := sketchPath.Join(sketchPath.Base() + ".ino")
+	require.NoError(t, mainFile.WriteFile([]byte(`struct Point { int x; };
+int add(int a, int b = 1) { return a + b; }
+void setup() { blink(); }
+void loop() {}
+void move(Point p) {}
+template <typename T> T twice(T v) { return v * 2; }
+`)))
+	require.NoError(t, sketchPath.Join("other.ino").WriteFile([]byte("void blink() {}\nstatic void hidden() {}\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	b := NewBuilder(s)
+	b.GeneratePrototypes = true
+	offset, source, _, err := b.sketchMergeSources(nil)
+	require.NoError(t, err)
+	require.Equal(t, 5, offset)
+	require.True(t, strings.HasPrefix(source, "#include <Arduino.h>\n"+
+		"void setup();\n"+
+		"void loop();\n"+
+		"void blink();\n"+
+		"#line 1 "+QuoteCppString(mainFile.String())+"\n"+
+		"struct Point { int x; };\n"), source)
+
+	// the diagnostics are mapped taking the prototypes into account
+	file, line := b.RemapDiagnostic(offset + 2)
+	require.Equal(t, mainFile, file)
+	require.Equal(t, 2, line)
+}
+
+func TestMergeSketchSourcesPrologueEpilogue(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+	_, plain, _, err := NewBuilder(s).sketchMergeSources(nil)
+	require.NoError(t, err)
+
+	b := NewBuilder(s)
+	b.Prologue = []string{`#include "build_config.h"`, "#define PROJECT 1"}
+	b.Epilogue = []string{"// end of sketch"}
+	offset, source, sourceMap, err := b.sketchMergeSources(nil)
+	require.NoError(t, err)
+	require.Equal(t, 4, offset)
+	require.Equal(t, "#include <Arduino.h>\n"+
+		"#include \"build_config.h\"\n"+
+		"#define PROJECT 1\n"+
+		strings.TrimPrefix(plain, "#include <Arduino.h>\n")+
+		"// end of sketch\n", source)
+	require.Equal(t, 5, sourceMap[0].StartLineInMerged)
+	file, line := b.RemapDiagnostic(5)
+	require.Equal(t, s.MainFile, file)
+	require.Equal(t, 1, line)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestWriteMergedSketchSource(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+	offset, source, err := NewBuilder(s).MergedSketchSource(nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	streamedOffset, err := NewBuilder(s).WriteMergedSketchSource(&buf, nil)
+	require.NoError(t, err)
+	require.Equal(t, offset, streamedOffset)
+	require.Equal(t, source, buf.String())
+
+	_, err = NewBuilder(s).WriteMergedSketchSource(failingWriter{}, nil)
+	require.EqualError(t, err, "write failed")
+}
+
+func TestMergeSketchSourcesStripsBOM(t *testing.T) {
+	sketchPath := paths.New(t.TempDir(), "SketchWithBOM")
+	require.NoError(t, sketchPath.MkdirAll())
+	bom := "\xEF\xBB\xBF"
+	require.NoError(t, sketchPath.Join("SketchWithBOM.ino").WriteFile([]byte(bom+"void setup() {}\nvoid loop() {}\n")))
+	require.NoError(t, sketchPath.Join("other.ino").WriteFile([]byte(bom+"void other() {}\n")))
+	require.NoError(t, sketchPath.Join("header.h").WriteFile([]byte(bom+"#define HEADER\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	buildPath := paths.New(t.TempDir())
+	offset, source, err := NewBuilder(s).PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Equal(t, 2, offset)
+	require.NotContains(t, source, bom)
+	require.True(t, strings.HasPrefix(source, "#include <Arduino.h>\n#line 1 "+QuoteCppString(s.MainFile.String())+"\nvoid setup() {}\n"))
+
+	header, err := buildPath.Join("header.h").ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, "#line 1 "+QuoteCppString(s.FullPath.Join("header.h").String())+"\n#define HEADER\n", string(header))
+}
+
 func TestCopyAdditionalFiles(t *testing.T) {
 	tmp := tmpDirOrDie()
 	defer tmp.RemoveAll()
@@ -109,7 +497,7 @@ func TestCopyAdditionalFiles(t *testing.T) {
 
 	// copy the sketch over, create a fake main file we don't care about it
 	// but we need it for `SketchLoad` to succeed later
-	err = sketchCopyAdditionalFiles(s1, tmp, nil)
+	err = NewBuilder(s1).sketchCopyAdditionalFiles(tmp, nil)
 	require.Nil(t, err)
 	fakeIno := tmp.Join(fmt.Sprintf("%s.ino", tmp.Base()))
 	require.Nil(t, fakeIno.WriteFile([]byte{}))
@@ -124,7 +512,7 @@ func TestCopyAdditionalFiles(t *testing.T) {
 	require.Nil(t, err)
 
 	// copy again
-	err = sketchCopyAdditionalFiles(s1, tmp, nil)
+	err = NewBuilder(s1).sketchCopyAdditionalFiles(tmp, nil)
 	require.Nil(t, err)
 
 	// verify file hasn't changed
@@ -132,3 +520,893 @@ func TestCopyAdditionalFiles(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, info1.ModTime(), info2.ModTime())
 }
+
+func TestCopyAdditionalFilesStartLine(t *testing.T) {
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+
+	b := NewBuilder(s)
+	b.AdditionalFilesStartLine = map[string]int{"header.h": 42}
+	require.NoError(t, b.sketchCopyAdditionalFiles(tmp, nil))
+
+	copied, err := tmp.Join("header.h").ReadFile()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(copied), "#line 42 "+QuoteCppString(s.FullPath.Join("header.h").String())+"\n"))
+
+	// the other files start at line 1
+	copied, err = tmp.Join("s_file.S").ReadFile()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(copied), "#line 1 "))
+
+	// the paths may use either separator
+	for _, key := range []string{"src/helper.h", "src\\helper.h", "./src/helper.h"} {
+		b.AdditionalFilesStartLine = map[string]int{key: 7}
+		require.NoError(t, b.sketchCopyAdditionalFiles(tmp, nil))
+		copied, err = tmp.Join("src", "helper.h").ReadFile()
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(copied), "#line 7 "), key)
+	}
+}
+
+func TestCopyAdditionalFilesData(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	buildPath := tmpDirOrDie()
+	defer buildPath.RemoveAll()
+	require.NoError(t, sketchPath.Join(sketchPath.Base()+".ino").WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	jsonData := []byte(`{"brightness": 10}`)
+	require.NoError(t, sketchPath.Join("config.json").WriteFile(jsonData))
+	binaryHeader := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	require.NoError(t, sketchPath.Join("image.h").WriteFile(binaryHeader))
+	require.NoError(t, sketchPath.Join("helper.h").WriteFile([]byte("#define A 1\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	require.NoError(t, NewBuilder(s).sketchCopyAdditionalFiles(buildPath, nil))
+
+	// the data files are copied verbatim
+	data, err := buildPath.Join("config.json").ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, jsonData, data)
+	data, err = buildPath.Join("image.h").ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, binaryHeader, data)
+	// the sources are tagged
+	data, err = buildPath.Join("helper.h").ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, "#line 1 "+QuoteCppString(sketchPath.Join("helper.h").String())+"\n#define A 1\n", string(data))
+}
+
+func TestLongPathError(t *testing.T) {
+	file := paths.New("sketch", "src", "helper.h")
+	cause := errors.New("the system cannot find the path specified")
+	shortPath := paths.New(t.TempDir(), "helper.h")
+	longPath := paths.New(t.TempDir(), strings.Repeat("nested", 50), "helper.h")
+
+	// only the long paths on Windows are reported
+	require.Equal(t, cause, longPathError("linux", file, longPath, cause))
+	require.Equal(t, cause, longPathError("windows", file, shortPath, cause))
+
+	err := longPathError("windows", file, longPath, cause)
+	var fileErr *SketchFileError
+	require.ErrorAs(t, err, &fileErr)
+	require.Equal(t, file, fileErr.File)
+	require.ErrorIs(t, err, cause)
+	require.Contains(t, err.Error(), longPath.String())
+	require.Contains(t, err.Error(), "260")
+}
+
+func TestCopyAdditionalFilesTemplateImplementation(t *testing.T) {
+	for _, name := range []string{"TestSketchWithTppFile", "TestSketchWithIppFile"} {
+		t.Run(name, func(t *testing.T) {
+			sketchPath := paths.New("testdata", name)
+			sk, err := sketch.New(sketchPath)
+			require.NoError(t, err)
+			require.Len(t, sk.AdditionalFiles, 1)
+			templateFile := sk.AdditionalFiles[0]
+
+			buildPath := paths.New(t.TempDir())
+			_, _, err = NewBuilder(sk).PrepareSketchBuildPath(nil, buildPath)
+			require.NoError(t, err)
+
+			// template implementation files are included like headers, so
+			// they are tagged with the #line directive of the original file
+			data, err := buildPath.Join(templateFile.Base()).ReadFile()
+			require.NoError(t, err)
+			require.Equal(t, "#line 1 "+QuoteCppString(templateFile.String())+"\n", string(data))
+
+			merged, err := buildPath.Join(name + ".ino.cpp").ReadFile()
+			require.NoError(t, err)
+			require.Contains(t, string(merged), `#include "`+templateFile.Base()+`"`)
+		})
+	}
+}
+
+func TestCopyAdditionalFilesInParallel(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+	require.True(t, s.AdditionalFiles.Len() > 1)
+
+	for _, jobs := range []int{1, 4} {
+		tmp := tmpDirOrDie()
+		defer tmp.RemoveAll()
+
+		b := NewBuilder(s)
+		b.Jobs = jobs
+		require.NoError(t, b.sketchCopyAdditionalFiles(tmp, nil))
+
+		for _, file := range s.AdditionalFiles {
+			relpath, err := s.FullPath.RelTo(file)
+			require.NoError(t, err)
+			original, err := file.ReadFile()
+			require.NoError(t, err)
+			copied, err := tmp.JoinPath(relpath).ReadFile()
+			require.NoError(t, err)
+			require.Equal(t, "#line 1 "+QuoteCppString(file.String())+"\n"+string(original), string(copied))
+		}
+	}
+
+	// a missing file makes the copy fail
+	s.AdditionalFiles.Add(s.FullPath.Join("missing.h"))
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+	b := NewBuilder(s)
+	b.Jobs = 4
+	require.Error(t, b.sketchCopyAdditionalFiles(tmp, nil))
+}
+
+func TestCopyAdditionalFilesPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported on Windows")
+	}
+	sketchPath := paths.New(t.TempDir(), "SketchWithScript")
+	require.NoError(t, sketchPath.MkdirAll())
+	require.NoError(t, sketchPath.Join("SketchWithScript.ino").WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	script := sketchPath.Join("post_build.json")
+	require.NoError(t, script.WriteFile([]byte("{}")))
+	require.NoError(t, os.Chmod(script.String(), 0755))
+	header := sketchPath.Join("header.h")
+	require.NoError(t, header.WriteFile([]byte("#define HEADER\n")))
+	require.NoError(t, os.Chmod(header.String(), 0444))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	buildPath := paths.New(t.TempDir())
+	for i := 0; i < 2; i++ {
+		require.NoError(t, NewBuilder(s).sketchCopyAdditionalFiles(buildPath, nil))
+		info, err := buildPath.Join("post_build.json").Stat()
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+		// files tagged with #line keep the permissions too, but stay writable
+		info, err = buildPath.Join("header.h").Stat()
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+		// a changed source must be rewritten
+		require.NoError(t, os.Chmod(header.String(), 0644))
+		require.NoError(t, header.WriteFile([]byte("#define HEADER 2\n")))
+		require.NoError(t, os.Chmod(header.String(), 0444))
+	}
+}
+
+func TestCopyAdditionalFilesLinkMode(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	require.NoError(t, sketchPath.Join(sketchPath.Base()+".ino").WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	require.NoError(t, sketchPath.Join("data.json").WriteFile([]byte("{}")))
+	require.NoError(t, sketchPath.Join("header.h").WriteFile([]byte("#define A 1\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+	require.Equal(t, 2, s.AdditionalFiles.Len())
+
+	for _, mode := range []AdditionalFilesMode{AdditionalFilesHardLink, AdditionalFilesSymlink} {
+		if mode == AdditionalFilesSymlink && runtime.GOOS == "windows" {
+			// creating symlinks may require additional privileges on Windows
+			continue
+		}
+		buildPath := tmpDirOrDie()
+		defer buildPath.RemoveAll()
+
+		b := NewBuilder(s)
+		b.AdditionalFilesMode = mode
+		require.NoError(t, b.sketchCopyAdditionalFiles(buildPath, nil))
+		require.NoError(t, b.sketchCopyAdditionalFiles(buildPath, nil))
+
+		// assets are linked...
+		require.True(t, isLinkedTo(buildPath.Join("data.json"), sketchPath.Join("data.json")))
+		if mode == AdditionalFilesSymlink {
+			info, err := os.Lstat(buildPath.Join("data.json").String())
+			require.NoError(t, err)
+			require.NotZero(t, info.Mode()&os.ModeSymlink)
+		}
+
+		// ...while headers are still tagged with the #line directive
+		require.False(t, isLinkedTo(buildPath.Join("header.h"), sketchPath.Join("header.h")))
+		header, err := buildPath.Join("header.h").ReadFile()
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(header), "#line 1 "))
+
+		// switching back to copy must not write through the link
+		require.NoError(t, NewBuilder(s).sketchCopyAdditionalFiles(buildPath, nil))
+		require.False(t, isLinkedTo(buildPath.Join("data.json"), sketchPath.Join("data.json")))
+		original, err := sketchPath.Join("data.json").ReadFile()
+		require.NoError(t, err)
+		require.Equal(t, "{}", string(original))
+	}
+}
+
+func TestPrepareSketchBuildPathStats(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+
+	b := NewBuilder(s)
+	_, _, err = b.PrepareSketchBuildPath(nil, tmp)
+	require.NoError(t, err)
+
+	expectedFiles := paths.PathList{tmp.Join(s.MainFile.Base() + ".cpp")}
+	for _, file := range s.AdditionalFiles {
+		relpath, err := s.FullPath.RelTo(file)
+		require.NoError(t, err)
+		expectedFiles.Add(tmp.JoinPath(relpath))
+	}
+	expectedBytes := int64(0)
+	for _, file := range expectedFiles {
+		info, err := file.Stat()
+		require.NoError(t, err)
+		expectedBytes += info.Size()
+	}
+
+	stats := b.PreparationStats()
+	require.Equal(t, len(expectedFiles), stats.FilesCopied)
+	require.Equal(t, expectedBytes, stats.BytesWritten)
+
+	// files already up to date are counted again
+	_, _, err = b.PrepareSketchBuildPath(nil, tmp)
+	require.NoError(t, err)
+	require.Equal(t, stats, b.PreparationStats())
+}
+
+func TestPrepareSketchBuildPathOutputBaseName(t *testing.T) {
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+
+	b := NewBuilder(s)
+	b.OutputBaseName = "project"
+	_, _, err = b.PrepareSketchBuildPath(nil, tmp)
+	require.Nil(t, err)
+	require.True(t, tmp.Join("TestLoadSketchFolder.ino.cpp").NotExist())
+
+	merged, err := tmp.Join("project.cpp").ReadFile()
+	require.Nil(t, err)
+	require.True(t, strings.HasPrefix(string(merged), "#include <Arduino.h>\n#line 1 "))
+}
+
+func TestPrepareSketchBuildPathCppMainFile(t *testing.T) {
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+	sketchPath := tmp.Join("sketch")
+	buildPath := tmp.Join("build")
+	require.NoError(t, sketchPath.MkdirAll())
+	mainFile := sketchPath.Join("sketch.cpp")
+	mainSrc := "int main() {\n  return 0;\n}\n"
+	require.NoError(t, mainFile.WriteFile([]byte(mainSrc)))
+
+	s := &sketch.Sketch{
+		Name:     "sketch",
+		MainFile: mainFile,
+		FullPath: sketchPath,
+	}
+
+	// a .cpp main file must be passed through without merging
+	offset, source, err := NewBuilder(s).PrepareSketchBuildPath(nil, buildPath)
+	require.Nil(t, err)
+	require.Equal(t, 0, offset)
+	require.Equal(t, mainSrc, source)
+	require.True(t, buildPath.Join("sketch.cpp.cpp").NotExist())
+	saved, err := buildPath.Join("sketch.cpp").ReadFile()
+	require.Nil(t, err)
+	require.Equal(t, mainSrc, string(saved))
+}
+
+func TestNeedsPreprocessing(t *testing.T) {
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+	sketchPath := tmp.Join("sketch")
+	buildPath := tmp.Join("build")
+	require.NoError(t, sketchPath.MkdirAll())
+	mainFile := sketchPath.Join("sketch.cpp")
+	mainSrc := "#include \"helper.h\"\nint main() {\n  return helper();\n}\n"
+	require.NoError(t, mainFile.WriteFile([]byte(mainSrc)))
+	helperFile := sketchPath.Join("helper.h")
+	require.NoError(t, helperFile.WriteFile([]byte("int helper() { return 0; }\n")))
+
+	// an all-.cpp sketch is copied without merging, even if NoMerge is set
+	s := &sketch.Sketch{
+		Name:            "sketch",
+		MainFile:        mainFile,
+		FullPath:        sketchPath,
+		AdditionalFiles: paths.PathList{helperFile},
+	}
+	b := NewBuilder(s)
+	b.NoMerge = true
+	require.False(t, b.NeedsPreprocessing())
+	offset, source, sourceMap, err := b.PrepareSketchBuildPathWithSourceMap(nil, buildPath)
+	require.NoError(t, err)
+	require.Equal(t, 0, offset)
+	require.Equal(t, mainSrc, source)
+	require.Len(t, sourceMap, 1)
+	require.Equal(t, mainFile, sourceMap[0].File)
+	saved, err := buildPath.Join("sketch.cpp").ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, mainSrc, string(saved))
+	require.True(t, buildPath.Join("helper.h").Exist())
+	planned, err := b.PlannedSketchFiles(buildPath)
+	require.NoError(t, err)
+	require.ElementsMatch(t, paths.PathList{buildPath.Join("sketch.cpp"), buildPath.Join("helper.h")}, planned)
+
+	// a sketch with .ino files and additional .cpp files must be merged
+	mixed, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+	require.True(t, NewBuilder(mixed).NeedsPreprocessing())
+}
+
+func TestNeedsPreprocessingCppProject(t *testing.T) {
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+	sketchPath := tmp.Join("project")
+	buildPath := tmp.Join("build")
+	require.NoError(t, sketchPath.MkdirAll())
+	mainFile := sketchPath.Join("project.ino")
+	mainSrc := "// the code is in main.cpp\n"
+	require.NoError(t, mainFile.WriteFile([]byte(mainSrc)))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	// an .ino file without code is a sketch if there are no C++ sources
+	require.True(t, NewBuilder(s).NeedsPreprocessing())
+
+	// the .ino files of a plain C++ project contain only comments
+	require.NoError(t, sketchPath.Join("main.cpp").WriteFile([]byte("int main() {\n  return 0;\n}\n")))
+	require.NoError(t, sketchPath.Join("other.ino").WriteFile([]byte("/*\n * nothing here\n */\n")))
+	s, err = sketch.New(sketchPath)
+	require.NoError(t, err)
+	b := NewBuilder(s)
+	require.False(t, b.NeedsPreprocessing())
+	offset, source, err := b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Equal(t, 0, offset)
+	require.Equal(t, mainSrc, source)
+	saved, err := buildPath.Join("project.ino.cpp").ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, mainSrc, string(saved))
+	require.True(t, buildPath.Join("main.cpp").Exist())
+
+	// the overrides are taken into account
+	overrides := map[string]string{"other.ino": "void helper() {}\n"}
+	_, source, err = b.PrepareSketchBuildPath(overrides, buildPath)
+	require.NoError(t, err)
+	require.Contains(t, source, "#include <Arduino.h>\n")
+	require.Contains(t, source, "void helper() {}\n")
+
+	require.NoError(t, sketchPath.Join("other.ino").WriteFile([]byte("void helper() {}\n")))
+	require.NoError(t, b.Reload())
+	require.True(t, b.NeedsPreprocessing())
+}
+
+func TestPrepareSketchBuildPathNoMerge(t *testing.T) {
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+
+	// a merged source left by a previous build must be removed
+	require.NoError(t, tmp.Join("TestLoadSketchFolder.ino.cpp").WriteFile([]byte{}))
+
+	b := NewBuilder(s)
+	b.NoMerge = true
+	overrides := map[string]string{"other.ino": "void other() {}\n"}
+	offset, source, err := b.PrepareSketchBuildPath(overrides, tmp)
+	require.Nil(t, err)
+	require.Equal(t, 0, offset)
+	require.Equal(t, "", source)
+	require.True(t, tmp.Join("TestLoadSketchFolder.ino.cpp").NotExist())
+
+	mainSrc, err := s.MainFile.ReadFile()
+	require.Nil(t, err)
+	copied, err := tmp.Join("TestLoadSketchFolder.ino").ReadFile()
+	require.Nil(t, err)
+	require.Equal(t, "#line 1 "+QuoteCppString(s.MainFile.String())+"\n"+string(mainSrc), string(copied))
+
+	copied, err = tmp.Join("other.ino").ReadFile()
+	require.Nil(t, err)
+	otherFile := s.FullPath.Join("other.ino")
+	require.Equal(t, "#line 1 "+QuoteCppString(otherFile.String())+"\nvoid other() {}\n", string(copied))
+
+	// the additional files are still copied
+	require.True(t, tmp.Join("header.h").Exist())
+}
+
+func TestPrepareSketchBuildPathSplitTranslationUnits(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	buildPath := tmpDirOrDie()
+	defer buildPath.RemoveAll()
+	mainFile := sketchPath.Join(sketchPath.Base() + ".ino")
+	otherFile := sketchPath.Join("other.ino")
+	require.NoError(t, mainFile.WriteFile([]byte("int helper() { return 3; }\nvoid setup() { blink(helper()); }\nvoid loop() {}\n")))
+	require.NoError(t, otherFile.WriteFile([]byte("void blink(int times) {\n  helper();\n  pause();\n}\nvoid pause() {}\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	b := NewBuilder(s)
+	b.SplitTranslationUnits = true
+	offset, merged, err := b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Equal(t, 3, offset)
+	require.Equal(t, "#include <Arduino.h>\n"+
+		"void blink(int times);\n"+
+		"#line 1 "+QuoteCppString(mainFile.String())+"\n"+
+		"int helper() { return 3; }\nvoid setup() { blink(helper()); }\nvoid loop() {}\n\n", merged)
+
+	unit, err := buildPath.Join("other.ino.cpp").ReadFile()
+	require.NoError(t, err)
+	// the functions of the unit may be used before their definition
+	require.Equal(t, "#include <Arduino.h>\n"+
+		"void blink(int times);\n"+
+		"void pause();\n"+
+		"int helper();\n"+
+		"#line 1 "+QuoteCppString(otherFile.String())+"\n"+
+		"void blink(int times) {\n  helper();\n  pause();\n}\nvoid pause() {}\n\n", string(unit))
+
+	// the units are removed once merged again
+	b.SplitTranslationUnits = false
+	_, merged, err = b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Contains(t, merged, "void pause() {}")
+	require.True(t, buildPath.Join("other.ino.cpp").NotExist())
+}
+
+func TestPrepareSketchBuildPathOverriddenFiles(t *testing.T) {
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+
+	overrides := map[string]string{
+		"other.ino": "void other() {}\n",
+		"header.h":  "#define A 1\n",
+		// not part of the sketch
+		"missing.h": "#define B 1\n",
+	}
+	b := NewBuilder(s)
+	_, _, err = b.PrepareSketchBuildPath(overrides, tmp)
+	require.Nil(t, err)
+	require.Equal(t, []string{"header.h", "other.ino"}, b.OverriddenFiles())
+
+	b.NoMerge = true
+	_, _, err = b.PrepareSketchBuildPath(overrides, tmp)
+	require.Nil(t, err)
+	require.Equal(t, []string{"header.h", "other.ino"}, b.OverriddenFiles())
+
+	// the list is reset on each run
+	_, _, err = b.PrepareSketchBuildPath(nil, tmp)
+	require.Nil(t, err)
+	require.Empty(t, b.OverriddenFiles())
+}
+
+// mapFileReader is a SketchFileReader serving the files in the map, keyed
+// by their base name
+type mapFileReader map[string]string
+
+func (r mapFileReader) ReadFile(file *paths.Path) ([]byte, error) {
+	if data, ok := r[file.Base()]; ok {
+		return []byte(data), nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func TestPrepareSketchBuildPathFileReader(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	buildPath := tmpDirOrDie()
+	defer buildPath.RemoveAll()
+	mainFile := sketchPath.Join(sketchPath.Base() + ".ino")
+	require.NoError(t, mainFile.WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	require.NoError(t, sketchPath.Join("header.h").WriteFile([]byte("#define ON_DISK\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	b := NewBuilder(s)
+	b.AdditionalFilesMode = AdditionalFilesSymlink
+	b.FileReader = mapFileReader{
+		mainFile.Base(): "#include \"header.h\"\nvoid setup() {}\nvoid loop() { IN_MEMORY; }\n",
+		"header.h":      "#define IN_MEMORY\n",
+	}
+	_, _, err = b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+
+	merged, err := buildPath.Join(mainFile.Base() + ".cpp").ReadFile()
+	require.NoError(t, err)
+	require.Contains(t, string(merged), "IN_MEMORY")
+	// the additional files are copied, not linked
+	header, err := buildPath.Join("header.h").ReadFile()
+	require.NoError(t, err)
+	require.Contains(t, string(header), "#define IN_MEMORY")
+	require.NotContains(t, string(header), "ON_DISK")
+
+	// the overrides take precedence
+	_, _, err = b.PrepareSketchBuildPath(map[string]string{"header.h": "#define OVERRIDE\n"}, buildPath)
+	require.NoError(t, err)
+	header, err = buildPath.Join("header.h").ReadFile()
+	require.NoError(t, err)
+	require.Contains(t, string(header), "#define OVERRIDE")
+
+	// errors are reported
+	delete(b.FileReader.(mapFileReader), "header.h")
+	_, _, err = b.PrepareSketchBuildPath(nil, buildPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestBuilderReload(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	require.NoError(t, sketchPath.Join(sketchPath.Base()+".ino").WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	b := NewBuilder(s)
+	_, source, err := b.MergedSketchSource(nil)
+	require.NoError(t, err)
+	require.NotContains(t, source, "void other()")
+
+	// a file added to the sketch is merged after the reload
+	require.NoError(t, sketchPath.Join("other.ino").WriteFile([]byte("void other() {}\n")))
+	require.NoError(t, b.Reload())
+	require.NotSame(t, s, b.Sketch())
+	_, source, err = b.MergedSketchSource(nil)
+	require.NoError(t, err)
+	require.Contains(t, source, "void other()")
+
+	// the sketch is kept if it can't be loaded
+	reloaded := b.Sketch()
+	require.NoError(t, sketchPath.RemoveAll())
+	require.Error(t, b.Reload())
+	require.Same(t, reloaded, b.Sketch())
+
+	b.SetSketch(s)
+	require.Same(t, s, b.Sketch())
+}
+
+func TestMergeSketchSourcesMainFileExtensions(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolderPde"))
+	require.Nil(t, err)
+
+	// .pde main files are merged by default
+	_, source, _, err := NewBuilder(s).sketchMergeSources(nil)
+	require.Nil(t, err)
+	require.Contains(t, source, "#line 1 ")
+
+	// ...but not if the recognized extensions are restricted to .ino
+	b := NewBuilder(s)
+	b.MainFileExtensions = []string{".ino"}
+	offset, source, _, err := b.sketchMergeSources(nil)
+	require.Nil(t, err)
+	require.Equal(t, 0, offset)
+	require.NotContains(t, source, "#line 1 ")
+}
+
+func TestPrepareSketchBuildPathPreservesMergedFile(t *testing.T) {
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.Nil(t, err)
+
+	_, _, err = PrepareSketchBuildPath(s, nil, tmp)
+	require.Nil(t, err)
+	mergedFile := tmp.Join("TestLoadSketchFolder.ino.cpp")
+	info1, err := mergedFile.Stat()
+	require.Nil(t, err)
+
+	// make sure a rewrite would produce a different timestamp
+	time.Sleep(10 * time.Millisecond)
+
+	// prepare again, the merged file must not be rewritten
+	_, _, err = PrepareSketchBuildPath(s, nil, tmp)
+	require.Nil(t, err)
+	info2, err := mergedFile.Stat()
+	require.Nil(t, err)
+	require.Equal(t, info1.ModTime(), info2.ModTime())
+}
+
+func TestSourceIncludesArduinoH(t *testing.T) {
+	require.True(t, SourceIncludesArduinoH("#include <Arduino.h>\n"))
+	require.True(t, SourceIncludesArduinoH("#include \"Arduino.h\"\n"))
+	require.True(t, SourceIncludesArduinoH("  \t#include <Arduino.h>\n"))
+	require.True(t, SourceIncludesArduinoH("#   include <Arduino.h>\n"))
+	require.True(t, SourceIncludesArduinoH("#include<Arduino.h>\n"))
+	require.True(t, SourceIncludesArduinoH("void setup() {}\n#include <Arduino.h>\n"))
+	require.False(t, SourceIncludesArduinoH("void setup() {}\n"))
+	require.False(t, SourceIncludesArduinoH("// #include <Arduino.h>\n"))
+	require.False(t, SourceIncludesArduinoH("#include <Arduino_h.h>\n"))
+}
+
+func TestWriteIfDifferent(t *testing.T) {
+	tmp := tmpDirOrDie()
+	defer tmp.RemoveAll()
+	dest := tmp.Join("data.bin")
+
+	// larger than the chunk used for the comparison
+	data := make([]byte, 200*1024+17)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	require.NoError(t, writeIfDifferent(data, dest))
+	equal, err := fileContentEquals(dest, data)
+	require.NoError(t, err)
+	require.True(t, equal)
+
+	// same content, the file is not rewritten
+	info1, err := dest.Stat()
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, writeIfDifferent(data, dest))
+	info2, err := dest.Stat()
+	require.NoError(t, err)
+	require.Equal(t, info1.ModTime(), info2.ModTime())
+
+	// same size, different content in the last chunk
+	changed := append([]byte{}, data...)
+	changed[len(changed)-1]++
+	equal, err = fileContentEquals(dest, changed)
+	require.NoError(t, err)
+	require.False(t, equal)
+	require.NoError(t, writeIfDifferent(changed, dest))
+	written, err := dest.ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, changed, written)
+
+	// different size
+	require.NoError(t, writeIfDifferent(data[:100], dest))
+	written, err = dest.ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, data[:100], written)
+
+	// empty content
+	require.NoError(t, writeIfDifferent([]byte{}, dest))
+	equal, err = fileContentEquals(dest, []byte{})
+	require.NoError(t, err)
+	require.True(t, equal)
+}
+
+func TestPrepareSketchBuildPathRemovesStaleFiles(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	buildPath := tmpDirOrDie()
+	defer buildPath.RemoveAll()
+	require.NoError(t, sketchPath.Join(sketchPath.Base()+".ino").WriteFile([]byte("#include \"header.h\"\nvoid setup() {}\nvoid loop() {}\n")))
+	require.NoError(t, sketchPath.Join("src").MkdirAll())
+	require.NoError(t, sketchPath.Join("header.h").WriteFile([]byte("#define A 1\n")))
+	require.NoError(t, sketchPath.Join("src", "helper.h").WriteFile([]byte("#define B 1\n")))
+	require.NoError(t, sketchPath.Join("src", "helper.cpp").WriteFile([]byte("int b = 1;\n")))
+
+	prepare := func(keepStaleFiles bool) {
+		s, err := sketch.New(sketchPath)
+		require.NoError(t, err)
+		b := NewBuilder(s)
+		b.KeepStaleFiles = keepStaleFiles
+		_, _, err = b.PrepareSketchBuildPath(nil, buildPath)
+		require.NoError(t, err)
+	}
+	prepare(false)
+	require.True(t, buildPath.Join("header.h").Exist())
+	require.True(t, buildPath.Join("src", "helper.h").Exist())
+	require.True(t, buildPath.Join("src", "helper.cpp").Exist())
+	// simulate a previous compilation
+	require.NoError(t, buildPath.Join("src", "helper.cpp.o").WriteFile([]byte{}))
+	require.NoError(t, buildPath.Join("src", "helper.cpp.d").WriteFile([]byte{}))
+
+	// the stale files may be kept
+	require.NoError(t, sketchPath.Join("header.h").Remove())
+	prepare(true)
+	require.True(t, buildPath.Join("header.h").Exist())
+
+	require.NoError(t, sketchPath.Join("src", "helper.cpp").Remove())
+	prepare(false)
+	require.False(t, buildPath.Join("header.h").Exist())
+	require.False(t, buildPath.Join("src", "helper.cpp").Exist())
+	require.False(t, buildPath.Join("src", "helper.cpp.o").Exist())
+	require.False(t, buildPath.Join("src", "helper.cpp.d").Exist())
+	require.True(t, buildPath.Join("src", "helper.h").Exist())
+	require.True(t, buildPath.Join(sketchPath.Base()+".ino.cpp").Exist())
+}
+
+func TestPrepareSketchBuildPathCache(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	buildPath := tmpDirOrDie()
+	defer buildPath.RemoveAll()
+	mainFile := sketchPath.Join(sketchPath.Base() + ".ino")
+	require.NoError(t, mainFile.WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	require.NoError(t, sketchPath.Join("header.h").WriteFile([]byte("#define A 1\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	b := NewBuilder(s)
+	b.CachePreparation = true
+	offset, merged, err := b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Equal(t, 2, b.PreparationStats().FilesCopied)
+
+	// a marker in the build path tells if the files have been copied again
+	cached := []byte("// cached\n")
+	markBuildPath := func() {
+		require.NoError(t, buildPath.Join("header.h").WriteFile(cached))
+	}
+	requireCacheHit := func() {
+		header, err := buildPath.Join("header.h").ReadFile()
+		require.NoError(t, err)
+		require.Equal(t, cached, header)
+		// the statistics of the cached preparation are reported again
+		require.Equal(t, 2, b.PreparationStats().FilesCopied)
+		require.NotZero(t, b.PreparationStats().BytesWritten)
+	}
+
+	// nothing is prepared again if nothing changed
+	markBuildPath()
+	cachedOffset, cachedMerged, err := b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Equal(t, offset, cachedOffset)
+	require.Equal(t, merged, cachedMerged)
+	requireCacheHit()
+
+	// the sketch files, the overrides and the options are part of the key
+	require.NoError(t, sketchPath.Join("header.h").WriteFile([]byte("#define A 2\n")))
+	_, _, err = b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Equal(t, 2, b.PreparationStats().FilesCopied)
+	header, err := buildPath.Join("header.h").ReadFile()
+	require.NoError(t, err)
+	require.Contains(t, string(header), "#define A 2")
+
+	overrides := map[string]string{mainFile.Base(): "void setup() {}\nvoid loop() { delay(1); }\n"}
+	_, merged, err = b.PrepareSketchBuildPath(overrides, buildPath)
+	require.NoError(t, err)
+	require.Contains(t, merged, "delay(1)")
+	require.Equal(t, []string{mainFile.Base()}, b.OverriddenFiles())
+	markBuildPath()
+	_, _, err = b.PrepareSketchBuildPath(overrides, buildPath)
+	require.NoError(t, err)
+	requireCacheHit()
+	require.Equal(t, []string{mainFile.Base()}, b.OverriddenFiles())
+
+	b.NoPrelude = true
+	_, merged, err = b.PrepareSketchBuildPath(overrides, buildPath)
+	require.NoError(t, err)
+	require.NotContains(t, merged, "#include <Arduino.h>")
+	require.Equal(t, 2, b.PreparationStats().FilesCopied)
+
+	// the output is prepared again if it has been removed from the build path
+	require.NoError(t, buildPath.Join(mainFile.Base()+".cpp").Remove())
+	_, _, err = b.PrepareSketchBuildPath(overrides, buildPath)
+	require.NoError(t, err)
+	require.Equal(t, 2, b.PreparationStats().FilesCopied)
+	require.True(t, buildPath.Join(mainFile.Base()+".cpp").Exist())
+}
+
+func TestPrepareSketchBuildPathCacheSourceEncoding(t *testing.T) {
+	sketchPath := tmpDirOrDie()
+	defer sketchPath.RemoveAll()
+	buildPath := tmpDirOrDie()
+	defer buildPath.RemoveAll()
+	mainFile := sketchPath.Join(sketchPath.Base() + ".ino")
+	require.NoError(t, mainFile.WriteFile([]byte("// caf\xe9 \x80\nvoid setup() {}\nvoid loop() {}\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	b := NewBuilder(s)
+	b.CachePreparation = true
+	_, merged, err := b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Contains(t, merged, "// caf\xe9 \x80\n")
+
+	// the encoding changes the merged source, so it's part of the key
+	b.SourceEncoding = SourceEncodingWindows1252
+	_, merged, err = b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Contains(t, merged, "// café €\n")
+	require.Equal(t, 1, b.PreparationStats().FilesCopied)
+
+	b.SourceEncoding = SourceEncodingLatin1
+	_, merged, err = b.PrepareSketchBuildPath(nil, buildPath)
+	require.NoError(t, err)
+	require.Contains(t, merged, "// café \u0080\n")
+	saved, err := buildPath.Join(mainFile.Base() + ".cpp").ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, merged, string(saved))
+}
+
+func TestPrepareSketchBuildPathConcurrent(t *testing.T) {
+	// run with -race to check the access to the state of the Builder
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+	b := NewBuilder(s)
+	b.CachePreparation = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		buildPath := tmpDirOrDie()
+		defer buildPath.RemoveAll()
+		overrides := map[string]string{}
+		if i%2 == 0 {
+			overrides[s.MainFile.Base()] = "void setup() {}\nvoid loop() {}\n"
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				_, merged, err := b.PrepareSketchBuildPath(overrides, buildPath)
+				require.NoError(t, err)
+				require.NotEmpty(t, merged)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				b.RemapDiagnostic(3)
+				b.MergeLayout()
+				b.PreparationStats()
+				b.OverriddenFiles()
+				_, _, err := b.MergedSketchSource(overrides)
+				require.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	file, line := b.RemapDiagnostic(3)
+	require.Equal(t, s.MainFile, file)
+	require.Equal(t, 1, line)
+}
+
+func TestPlannedSketchFiles(t *testing.T) {
+	s, err := sketch.New(paths.New("testdata", "TestLoadSketchFolder"))
+	require.NoError(t, err)
+
+	for _, mode := range []string{"merge", "split", "no-merge"} {
+		t.Run(mode, func(t *testing.T) {
+			buildPath := paths.New(t.TempDir())
+			b := NewBuilder(s)
+			b.SplitTranslationUnits = mode == "split"
+			b.NoMerge = mode == "no-merge"
+
+			// nothing is written
+			planned, err := b.PlannedSketchFiles(buildPath)
+			require.NoError(t, err)
+			files, err := buildPath.ReadDirRecursive()
+			require.NoError(t, err)
+			require.Empty(t, files)
+
+			// the planned files are the ones placed in the build path
+			_, _, err = b.PrepareSketchBuildPath(nil, buildPath)
+			require.NoError(t, err)
+			files, err = buildPath.ReadDirRecursive()
+			require.NoError(t, err)
+			files.FilterOutDirs()
+			planned.Sort()
+			files.Sort()
+			require.Equal(t, planned, files)
+		})
+	}
+}
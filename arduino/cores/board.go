@@ -180,6 +180,13 @@ func (b *Board) GetIdentificationProperties() []*properties.Map {
 // IsBoardMatchingIDProperties returns true if the board match the given
 // upload port identification properties
 func (b *Board) IsBoardMatchingIDProperties(query *properties.Map) bool {
+	// First check the identification properties with sub index "upload_port.N.xxx"
+	return matchIDProperties(b.GetIdentificationProperties(), query)
+}
+
+// matchIDProperties returns true if at least one of the given sets of
+// identification properties match the "query"
+func matchIDProperties(idPropsList []*properties.Map, query *properties.Map) bool {
 	// check checks if the given set of properties p match the "query"
 	check := func(p *properties.Map) bool {
 		for k, v := range p.AsMap() {
@@ -190,8 +197,7 @@ func (b *Board) IsBoardMatchingIDProperties(query *properties.Map) bool {
 		return true
 	}
 
-	// First check the identification properties with sub index "upload_port.N.xxx"
-	for _, idProps := range b.GetIdentificationProperties() {
+	for _, idProps := range idPropsList {
 		if check(idProps) {
 			return true
 		}
@@ -23,3 +23,24 @@ type Programmer struct {
 	Properties      *properties.Map
 	PlatformRelease *PlatformRelease
 }
+
+// IsProgrammerMatchingIDProperties returns true if the programmer match the
+// given port identification properties. As for the boards, the programmer
+// identification properties are the ones with sub index "upload_port.N.xxx".
+func (p *Programmer) IsProgrammerMatchingIDProperties(query *properties.Map) bool {
+	return matchIDProperties(p.Properties.ExtractSubIndexSets("upload_port"), query)
+}
+
+// SupportsUpload returns true if the programmer can be used to upload a
+// sketch, that is if it defines the program.* properties used to select and
+// run the upload tool.
+func (p *Programmer) SupportsUpload() bool {
+	return p.Properties.SubTree("program").Size() > 0
+}
+
+// SupportsDebug returns true if the programmer can be used to debug a
+// sketch, that is if it defines the debug.* properties used to configure the
+// debug session.
+func (p *Programmer) SupportsDebug() bool {
+	return p.Properties.SubTree("debug").Size() > 0
+}
@@ -0,0 +1,79 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package cores
+
+import (
+	"testing"
+
+	"github.com/arduino/go-properties-orderedmap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgrammerMatching(t *testing.T) {
+	atmelIce := &Programmer{
+		Name: "Atmel-ICE",
+		Properties: properties.NewFromHashmap(map[string]string{
+			"protocol":          "atmelice_isp",
+			"upload_port.0.vid": "0x03eb",
+			"upload_port.0.pid": "0x2141",
+		}),
+	}
+	require.True(t, atmelIce.IsProgrammerMatchingIDProperties(properties.NewFromHashmap(map[string]string{
+		"vid":    "0x03EB",
+		"pid":    "0x2141",
+		"serial": "J41800012345",
+	})))
+	require.False(t, atmelIce.IsProgrammerMatchingIDProperties(properties.NewFromHashmap(map[string]string{
+		"vid": "0x03eb",
+		"pid": "0x2104",
+	})))
+
+	// Programmers without identification properties never match
+	usbasp := &Programmer{
+		Name: "USBasp",
+		Properties: properties.NewFromHashmap(map[string]string{
+			"protocol": "usbasp",
+		}),
+	}
+	require.False(t, usbasp.IsProgrammerMatchingIDProperties(properties.NewFromHashmap(map[string]string{
+		"vid": "0x16c0",
+		"pid": "0x05dc",
+	})))
+}
+
+func TestProgrammerCapabilities(t *testing.T) {
+	avrisp := &Programmer{
+		Name: "AVR ISP",
+		Properties: properties.NewFromHashmap(map[string]string{
+			"protocol":     "stk500v1",
+			"program.tool": "avrdude",
+		}),
+	}
+	require.True(t, avrisp.SupportsUpload())
+	require.False(t, avrisp.SupportsDebug())
+
+	jlink := &Programmer{
+		Name: "Segger J-Link",
+		Properties: properties.NewFromHashmap(map[string]string{
+			"protocol":                "jlink",
+			"program.tool.default":    "openocd",
+			"debug.server":            "jlink",
+			"debug.server.jlink.path": "JLinkGDBServerCL",
+		}),
+	}
+	require.True(t, jlink.SupportsUpload())
+	require.True(t, jlink.SupportsDebug())
+}
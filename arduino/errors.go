@@ -349,6 +349,25 @@ func (e *ProgrammerNotFoundError) ToRPCStatus() *status.Status {
 	return status.New(codes.NotFound, e.Error())
 }
 
+// DebugServerNotFoundError is returned when the requested debug server is
+// not available for the board
+type DebugServerNotFoundError struct {
+	Server    string
+	Available []string
+}
+
+func (e *DebugServerNotFoundError) Error() string {
+	if len(e.Available) == 0 {
+		return tr("Debug server '%s' not found, no debug servers available", e.Server)
+	}
+	return tr("Debug server '%[1]s' not found, available servers: %[2]s", e.Server, strings.Join(e.Available, ", "))
+}
+
+// ToRPCStatus converts the error into a *status.Status
+func (e *DebugServerNotFoundError) ToRPCStatus() *status.Status {
+	return status.New(codes.NotFound, e.Error())
+}
+
 // MonitorNotFoundError is returned when the pluggable monitor is not found
 type MonitorNotFoundError struct {
 	Monitor string
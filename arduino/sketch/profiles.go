@@ -31,11 +31,13 @@ import (
 
 // Project represents the sketch project file
 type Project struct {
-	Profiles        Profiles `yaml:"profiles"`
-	DefaultProfile  string   `yaml:"default_profile"`
-	DefaultFqbn     string   `yaml:"default_fqbn"`
-	DefaultPort     string   `yaml:"default_port,omitempty"`
-	DefaultProtocol string   `yaml:"default_protocol,omitempty"`
+	Profiles          Profiles `yaml:"profiles"`
+	DefaultProfile    string   `yaml:"default_profile"`
+	DefaultFqbn       string   `yaml:"default_fqbn"`
+	DefaultPort       string   `yaml:"default_port,omitempty"`
+	DefaultProtocol   string   `yaml:"default_protocol,omitempty"`
+	DefaultProgrammer string   `yaml:"default_programmer,omitempty"`
+	NoPrelude         bool     `yaml:"no_prelude,omitempty"`
 }
 
 // AsYaml outputs the sketch project file as YAML
@@ -58,6 +60,12 @@ func (p *Project) AsYaml() string {
 	if p.DefaultProtocol != "" {
 		res += fmt.Sprintf("default_protocol: %s\n", p.DefaultProtocol)
 	}
+	if p.DefaultProgrammer != "" {
+		res += fmt.Sprintf("default_programmer: %s\n", p.DefaultProgrammer)
+	}
+	if p.NoPrelude {
+		res += "no_prelude: true\n"
+	}
 	return res
 }
 
@@ -19,9 +19,11 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"io/fs"
 	"sort"
 	"strings"
 
+	"github.com/arduino/arduino-cli/arduino/cores"
 	"github.com/arduino/arduino-cli/arduino/globals"
 	"github.com/arduino/arduino-cli/i18n"
 	"github.com/arduino/go-paths-helper"
@@ -41,6 +43,15 @@ type Sketch struct {
 
 var tr = i18n.Tr
 
+var (
+	// ErrSketchNotFound is matched (using errors.Is) by the errors returned
+	// when the sketch path does not exist
+	ErrSketchNotFound = errors.New("sketch not found")
+	// ErrNotASketch is matched (using errors.Is) by the errors returned when
+	// the sketch path exists but doesn't contain a valid sketch
+	ErrNotASketch = errors.New("not a sketch")
+)
+
 // New creates an Sketch instance by reading all the files composing a sketch and grouping them
 // by file type.
 func New(path *paths.Path) (*Sketch, error) {
@@ -50,9 +61,13 @@ func New(path *paths.Path) (*Sketch, error) {
 
 	path = path.Canonical()
 	if exist, err := path.ExistCheck(); err != nil {
-		return nil, fmt.Errorf("%s: %s", tr("sketch path is not valid"), err)
+		return nil, fmt.Errorf("%s: %w", tr("sketch path is not valid"), err)
 	} else if !exist {
-		return nil, fmt.Errorf("%s: %s", tr("no such file or directory"), path)
+		return nil, &loadError{
+			kind:    ErrSketchNotFound,
+			message: fmt.Sprintf("%s: %s", tr("no such file or directory"), path),
+			cause:   fs.ErrNotExist,
+		}
 	}
 	if _, validIno := globals.MainFileValidExtensions[path.Ext()]; validIno && !path.IsDir() {
 		path = path.Parent()
@@ -73,7 +88,10 @@ func New(path *paths.Path) (*Sketch, error) {
 		}
 	}
 	if mainFile == nil {
-		return nil, fmt.Errorf(tr("main file missing from sketch: %s", path.Join(path.Base()+globals.MainFileValidExtension)))
+		return nil, &loadError{
+			kind:    ErrNotASketch,
+			message: tr("main file missing from sketch: %s", path.Join(path.Base()+globals.MainFileValidExtension)),
+		}
 	}
 
 	sketch := &Sketch{
@@ -247,6 +265,12 @@ func (s *Sketch) GetDefaultPortAddressAndProtocol() (string, string) {
 	return s.Project.DefaultPort, s.Project.DefaultProtocol
 }
 
+// GetDefaultProgrammer returns the default programmer for the sketch (from the sketch.yaml project
+// file), or the empty string if not set.
+func (s *Sketch) GetDefaultProgrammer() string {
+	return s.Project.DefaultProgrammer
+}
+
 // SetDefaultFQBN sets the default FQBN for the sketch and saves it in the sketch.yaml project file.
 func (s *Sketch) SetDefaultFQBN(fqbn string) error {
 	s.Project.DefaultFqbn = fqbn
@@ -274,6 +298,31 @@ func (e *InvalidSketchFolderNameError) Error() string {
 	return tr("no valid sketch found in %[1]s: missing %[2]s", e.SketchFolder, e.SketchFile)
 }
 
+// Is makes InvalidSketchFolderNameError match ErrNotASketch
+func (e *InvalidSketchFolderNameError) Is(target error) bool {
+	return target == ErrNotASketch
+}
+
+// loadError is an error returned by New, it matches the sentinel error kind
+// while keeping the underlying cause available through errors.Unwrap
+type loadError struct {
+	kind    error
+	message string
+	cause   error
+}
+
+func (e *loadError) Error() string {
+	return e.message
+}
+
+func (e *loadError) Unwrap() error {
+	return e.cause
+}
+
+func (e *loadError) Is(target error) bool {
+	return target == e.kind
+}
+
 // CheckForPdeFiles returns all files ending with .pde extension
 // in sketch, this is mainly used to warn the user that these files
 // must be changed to .ino extension.
@@ -292,11 +341,23 @@ func CheckForPdeFiles(sketch *paths.Path) []*paths.Path {
 }
 
 // DefaultBuildPath generates the default build directory for a given sketch.
-// The build path is in a temporary directory and is unique for each sketch.
+// The build path is in a temporary directory and is unique for each sketch:
+// it's named after the sketch Hash, so it doesn't depend on the board. This is
+// the build path used by compile, upload and debug when none is given; a build
+// for another board reuses it, after the builder wipes the previous build.
 func (s *Sketch) DefaultBuildPath() *paths.Path {
 	return paths.TempDir().Join("arduino", "sketches", s.Hash())
 }
 
+// BuildPathFor returns the build path used for the given sketch when it's
+// built for the board in fqbn and no build path is given. Currently this is
+// the DefaultBuildPath of the sketch for every board: the fqbn is taken so
+// that compile, upload and debug don't depend on this and always agree on
+// where the build artifacts are.
+func BuildPathFor(sk *Sketch, fqbn *cores.FQBN) *paths.Path {
+	return sk.DefaultBuildPath()
+}
+
 // Hash generate a unique hash for the given sketch.
 func (s *Sketch) Hash() string {
 	path := s.FullPath.String()
@@ -16,11 +16,14 @@
 package sketch
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/arduino/arduino-cli/arduino/cores"
 	"github.com/arduino/go-paths-helper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -112,6 +115,20 @@ func TestNewSketchWrongMain(t *testing.T) {
 	require.Contains(t, err.Error(), expectedError)
 }
 
+func TestNewSketchErrorKinds(t *testing.T) {
+	_, err := New(paths.New("testdata", "SketchThatDoesNotExist"))
+	require.ErrorIs(t, err, ErrSketchNotFound)
+	require.NotErrorIs(t, err, ErrNotASketch)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = New(paths.New("testdata", "SketchWithWrongMain"))
+	require.ErrorIs(t, err, ErrNotASketch)
+	require.NotErrorIs(t, err, ErrSketchNotFound)
+
+	var folderNameErr error = &InvalidSketchFolderNameError{}
+	require.True(t, errors.Is(folderNameErr, ErrNotASketch))
+}
+
 func TestNewSketchCasingWrong(t *testing.T) {
 	{
 		sketchPath := paths.New("testdata", "SketchWithWrongMain")
@@ -291,6 +308,22 @@ func TestGenBuildPath(t *testing.T) {
 	assert.Equal(t, "ACBD18DB4CC2F85CEDEF654FCCC4A4D8", (&Sketch{FullPath: paths.New("foo")}).Hash())
 }
 
+func TestBuildPathFor(t *testing.T) {
+	sk := &Sketch{FullPath: paths.New("foo")}
+	uno, err := cores.ParseFQBN("arduino:avr:uno")
+	require.NoError(t, err)
+	mkr1000, err := cores.ParseFQBN("arduino:samd:mkr1000")
+	require.NoError(t, err)
+
+	want := paths.TempDir().Join("arduino", "sketches", "ACBD18DB4CC2F85CEDEF654FCCC4A4D8")
+	require.True(t, BuildPathFor(sk, uno).EquivalentTo(want))
+	require.True(t, BuildPathFor(sk, mkr1000).EquivalentTo(want))
+	require.True(t, BuildPathFor(sk, uno).EquivalentTo(sk.DefaultBuildPath()))
+
+	other := &Sketch{FullPath: paths.New("bar")}
+	require.False(t, BuildPathFor(other, uno).EquivalentTo(want))
+}
+
 func TestCheckForPdeFiles(t *testing.T) {
 	sketchPath := paths.New("testdata", "SketchSimple")
 	files := CheckForPdeFiles(sketchPath)
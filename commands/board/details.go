@@ -22,6 +22,7 @@ import (
 	"github.com/arduino/arduino-cli/arduino/cores"
 	"github.com/arduino/arduino-cli/arduino/utils"
 	"github.com/arduino/arduino-cli/commands"
+	"github.com/arduino/arduino-cli/commands/debug"
 	rpc "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/commands/v1"
 )
 
@@ -67,9 +68,7 @@ func Details(ctx context.Context, req *rpc.BoardDetailsRequest) (*rpc.BoardDetai
 	details.DebuggingSupported = boardProperties.ContainsKey("debug.executable") ||
 		boardPlatform.Properties.ContainsKey("debug.executable") ||
 		(boardRefPlatform != nil && boardRefPlatform.Properties.ContainsKey("debug.executable")) ||
-		// HOTFIX: Remove me when the `arduino:samd` core is updated
-		boardPlatform.String() == "arduino:samd@1.8.9" ||
-		boardPlatform.String() == "arduino:samd@1.8.8"
+		debug.GetPlatformDebugOverrides(boardPlatform).ContainsKey("debug.executable")
 
 	details.Package = &rpc.Package{
 		Name:       boardPackage.Name,
@@ -143,9 +142,11 @@ func Details(ctx context.Context, req *rpc.BoardDetailsRequest) (*rpc.BoardDetai
 	details.Programmers = []*rpc.Programmer{}
 	for id, p := range boardPlatform.Programmers {
 		details.Programmers = append(details.Programmers, &rpc.Programmer{
-			Platform: boardPlatform.Platform.Name,
-			Id:       id,
-			Name:     p.Name,
+			Platform:      boardPlatform.Platform.Name,
+			Id:            id,
+			Name:          p.Name,
+			UploadCapable: p.SupportsUpload(),
+			DebugCapable:  p.SupportsDebug(),
 		})
 	}
 
@@ -24,7 +24,6 @@ import (
 
 	"github.com/arduino/arduino-cli/arduino"
 	bldr "github.com/arduino/arduino-cli/arduino/builder"
-	"github.com/arduino/arduino-cli/arduino/cores"
 	"github.com/arduino/arduino-cli/arduino/sketch"
 	"github.com/arduino/arduino-cli/arduino/utils"
 	"github.com/arduino/arduino-cli/buildcache"
@@ -70,37 +69,16 @@ func Compile(ctx context.Context, req *rpc.CompileRequest, outStream, errStream
 	}
 
 	logrus.Tracef("Compile %s for %s started", req.GetSketchPath(), req.GetFqbn())
-	if req.GetSketchPath() == "" {
-		return nil, &arduino.MissingSketchPathError{}
-	}
-	sketchPath := paths.New(req.GetSketchPath())
-	sk, err := sketch.New(sketchPath)
-	if err != nil {
-		return nil, &arduino.CantOpenSketchError{Cause: err}
-	}
-
-	fqbnIn := req.GetFqbn()
-	if fqbnIn == "" && sk != nil {
-		fqbnIn = sk.GetDefaultFQBN()
-	}
-	if fqbnIn == "" {
-		return nil, &arduino.MissingFQBNError{}
-	}
-
-	fqbn, err := cores.ParseFQBN(fqbnIn)
-	if err != nil {
-		return nil, &arduino.InvalidFQBNError{Cause: err}
-	}
-	targetPackage, targetPlatform, targetBoard, buildProperties, buildPlatform, err := pme.ResolveFQBN(fqbn)
+	target, err := commands.ResolveSketchTarget(pme, &commands.SketchTargetRequest{
+		SketchPath: req.GetSketchPath(),
+		Fqbn:       req.GetFqbn(),
+	})
 	if err != nil {
-		if targetPlatform == nil {
-			return nil, &arduino.PlatformNotFoundError{
-				Platform: fmt.Sprintf("%s:%s", fqbn.Package, fqbn.PlatformArch),
-				Cause:    fmt.Errorf(tr("platform not installed")),
-			}
-		}
-		return nil, &arduino.InvalidFQBNError{Cause: err}
+		return nil, err
 	}
+	sk, fqbn := target.Sketch, target.FQBN
+	targetPackage, targetPlatform, targetBoard := target.Package, target.Platform, target.Board
+	buildProperties, buildPlatform := target.BoardProperties, target.BuildPlatform
 
 	r = &rpc.CompileResponse{}
 	r.BoardPlatform = targetPlatform.ToRPCPlatformReference()
@@ -139,7 +117,7 @@ func Compile(ctx context.Context, req *rpc.CompileRequest, outStream, errStream
 		}
 	}
 	if buildPath == nil {
-		buildPath = sk.DefaultBuildPath()
+		buildPath = sketch.BuildPathFor(sk, fqbn)
 	}
 	if err = buildPath.MkdirAll(); err != nil {
 		return nil, &arduino.PermissionDeniedError{Message: tr("Cannot create build directory"), Cause: err}
@@ -210,6 +188,17 @@ func Compile(ctx context.Context, req *rpc.CompileRequest, outStream, errStream
 		builderCtx.CoreBuildCachePath = buildCachePath.Join("core")
 	}
 
+	if req.GetSketchObjectCachePath() != "" {
+		sketchObjectCachePath, err := paths.New(req.GetSketchObjectCachePath()).Abs()
+		if err != nil {
+			return nil, &arduino.PermissionDeniedError{Message: tr("Cannot create sketch object cache directory"), Cause: err}
+		}
+		if err := sketchObjectCachePath.MkdirAll(); err != nil {
+			return nil, &arduino.PermissionDeniedError{Message: tr("Cannot create sketch object cache directory"), Cause: err}
+		}
+		builderCtx.SketchObjectCachePath = sketchObjectCachePath
+	}
+
 	builderCtx.BuiltInLibrariesDirs = configuration.IDEBuiltinLibrariesDir(configuration.Settings)
 
 	builderCtx.Stdout = outStream
@@ -257,6 +246,15 @@ func Compile(ctx context.Context, req *rpc.CompileRequest, outStream, errStream
 		return r, compileErr
 	}
 
+	if req.GetSyntaxCheckOnly() {
+		// Just check the sketch sources and exit
+		compileErr := builder.RunSyntaxCheck(builderCtx)
+		if compileErr != nil {
+			compileErr = &arduino.CompileFailedError{Message: compileErr.Error()}
+		}
+		return r, compileErr
+	}
+
 	defer func() {
 		importedLibs := []*rpc.Library{}
 		for _, lib := range builderCtx.ImportedLibraries {
@@ -355,7 +353,7 @@ func Compile(ctx context.Context, req *rpc.CompileRequest, outStream, errStream
 
 	r.ExecutableSectionsSize = builderCtx.ExecutableSectionsSize.ToRPCExecutableSectionSizeArray()
 
-	logrus.Tracef("Compile %s for %s successful", sk.Name, fqbnIn)
+	logrus.Tracef("Compile %s for %s successful", sk.Name, fqbn)
 
 	return r, nil
 }
@@ -71,3 +71,8 @@ func (s *DebugService) Debug(stream dbg.DebugService_DebugServer) error {
 func (s *DebugService) GetDebugConfig(ctx context.Context, req *dbg.DebugConfigRequest) (*dbg.GetDebugConfigResponse, error) {
 	return cmd.GetDebugConfig(ctx, req)
 }
+
+// ListDebuggableBoards returns the installed boards and their debugging support
+func (s *DebugService) ListDebuggableBoards(ctx context.Context, req *dbg.ListDebuggableBoardsRequest) (*dbg.ListDebuggableBoardsResponse, error) {
+	return cmd.ListDebuggableBoards(ctx, req)
+}
@@ -0,0 +1,74 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package debug
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/arduino/arduino-cli/arduino"
+	dbg "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/debug/v1"
+)
+
+// getCortexDebugLaunchConfig returns a JSON object that can be used as a
+// configuration entry of a VS Code launch.json with the cortex-debug
+// extension. The optional fields are added only if the board provides them.
+// The debug.cortex-debug.custom.* properties are copied at the top level of
+// the object, the other debug.cortex-debug.* properties as they are.
+func getCortexDebugLaunchConfig(debugInfo *dbg.GetDebugConfigResponse, attach bool) (string, error) {
+	config := map[string]interface{}{
+		"name":       "Arduino",
+		"type":       "cortex-debug",
+		"request":    "launch",
+		"cwd":        "${workspaceRoot}",
+		"executable": filepath.ToSlash(debugInfo.GetExecutable()),
+		"servertype": debugInfo.GetServer(),
+	}
+	if attach {
+		config["request"] = "attach"
+	}
+	if serverPath := debugInfo.GetServerPath(); serverPath != "" {
+		config["serverpath"] = filepath.ToSlash(serverPath)
+	}
+	if toolchainPath := debugInfo.GetToolchainPath(); toolchainPath != "" {
+		config["armToolchainPath"] = filepath.ToSlash(toolchainPath)
+	}
+	if toolchainPrefix := strings.TrimSuffix(debugInfo.GetToolchainPrefix(), "-"); toolchainPrefix != "" {
+		config["toolchainPrefix"] = toolchainPrefix
+	}
+	if svdFile := debugInfo.GetSvdFile(); svdFile != "" {
+		config["svdFile"] = filepath.ToSlash(svdFile)
+	}
+	if debugInfo.GetServer() == "openocd" {
+		serverConfig := debugInfo.GetServerConfiguration()
+		if script := serverConfig["script"]; script != "" {
+			config["configFiles"] = []string{filepath.ToSlash(script)}
+		}
+		if scriptsDir := serverConfig["scripts_dir"]; scriptsDir != "" {
+			config["searchDir"] = []string{filepath.ToSlash(scriptsDir)}
+		}
+	}
+	for key, value := range debugInfo.GetCortexDebugConfiguration() {
+		config[strings.TrimPrefix(key, "custom.")] = value
+	}
+
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", &arduino.FailedDebugError{Message: tr("Cannot generate the cortex-debug launch configuration"), Cause: err}
+	}
+	return string(data), nil
+}
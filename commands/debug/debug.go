@@ -158,21 +158,8 @@ func getCommandLine(req *dbg.DebugConfigRequest, pme *packagemanager.Explorer) (
 	// Extract path to GDB Server
 	switch debugInfo.GetServer() {
 	case "openocd":
-		serverCmd := fmt.Sprintf(`target extended-remote | "%s"`, debugInfo.ServerPath)
-
-		if cfg := debugInfo.ServerConfiguration["scripts_dir"]; cfg != "" {
-			serverCmd += fmt.Sprintf(` -s "%s"`, cfg)
-		}
-
-		if script := debugInfo.ServerConfiguration["script"]; script != "" {
-			serverCmd += fmt.Sprintf(` --file "%s"`, script)
-		}
-
-		serverCmd += ` -c "gdb_port pipe"`
-		serverCmd += ` -c "telnet_port 0"`
-
 		add("-ex")
-		add(serverCmd)
+		add(openocdTargetCommand(debugInfo))
 
 	default:
 		return nil, &arduino.FailedDebugError{Message: tr("GDB server '%s' is not supported", debugInfo.GetServer())}
@@ -189,3 +176,49 @@ func getCommandLine(req *dbg.DebugConfigRequest, pme *packagemanager.Explorer) (
 
 	return cmdArgs, nil
 }
+
+// openocdTargetCommand returns the GDB command that starts OpenOCD and
+// connects to it through a pipe.
+func openocdTargetCommand(debugInfo *dbg.GetDebugConfigResponse) string {
+	serverCmd := fmt.Sprintf(`target extended-remote | "%s"`, debugInfo.ServerPath)
+
+	if cfg := debugInfo.ServerConfiguration["scripts_dir"]; cfg != "" {
+		serverCmd += fmt.Sprintf(` -s "%s"`, cfg)
+	}
+
+	if script := debugInfo.ServerConfiguration["script"]; script != "" {
+		serverCmd += fmt.Sprintf(` --file "%s"`, script)
+	}
+
+	serverCmd += ` -c "gdb_port pipe"`
+	serverCmd += ` -c "telnet_port 0"`
+	return serverCmd
+}
+
+// getGDBInitScript returns a GDB script that loads the executable in the
+// board through the debug server. OpenOCD is started through a pipe, the
+// other servers are expected to be already listening on the host:port set
+// in the debug.server.SERVER.address property. If attach is true the
+// executable is used only for its symbols: it's not loaded and the board is
+// not reset.
+func getGDBInitScript(debugInfo *dbg.GetDebugConfigResponse, attach bool) (string, error) {
+	var target string
+	if debugInfo.GetServer() == "openocd" {
+		target = openocdTargetCommand(debugInfo)
+	} else if address := debugInfo.GetServerConfiguration()["address"]; address != "" {
+		target = "target extended-remote " + address
+	} else {
+		return "", &arduino.FailedDebugError{Message: tr("Cannot generate a GDB script for the GDB server '%s'", debugInfo.GetServer())}
+	}
+
+	script := fmt.Sprintf("file \"%s\"\n", filepath.ToSlash(debugInfo.GetExecutable()))
+	for _, executable := range debugInfo.GetAdditionalExecutables() {
+		script += fmt.Sprintf("add-symbol-file \"%s\"\n", filepath.ToSlash(executable))
+	}
+	script += filepath.ToSlash(target) + "\n"
+	if !attach {
+		script += "load\n"
+		script += "monitor reset\n"
+	}
+	return script, nil
+}
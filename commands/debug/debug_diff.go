@@ -0,0 +1,125 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package debug
+
+import (
+	"sort"
+	"strings"
+
+	dbg "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/debug/v1"
+)
+
+// ConfigDifference is a difference between two debug configurations.
+type ConfigDifference struct {
+	// Field is the name of the field of the GetDebugConfigResponse, as in the
+	// protocol definition (for example "server_path")
+	Field string
+	// Key is the differing key of the map fields (for example
+	// "server_configuration"), empty for the other fields
+	Key string
+	// A and B are the values of the field in the two configurations, an empty
+	// string for a key missing from a map
+	A, B string
+	// MissingInA and MissingInB are true if the Key is missing from the map
+	// of the respective configuration
+	MissingInA, MissingInB bool
+}
+
+func (d *ConfigDifference) String() string {
+	name := d.Field
+	if d.Key != "" {
+		name += "." + d.Key
+	}
+	a, b := "'"+d.A+"'", "'"+d.B+"'"
+	if d.MissingInA {
+		a = "(missing)"
+	}
+	if d.MissingInB {
+		b = "(missing)"
+	}
+	return name + ": " + a + " != " + b
+}
+
+// DiffDebugConfigs returns the differences between the debug configurations
+// a and b, in the order of the fields of the protocol definition. The keys of the maps, like the
+// server_configuration and the toolchain_configuration, are compared one by
+// one, the missing_tools and the warnings are compared regardless of their
+// order.
+func DiffDebugConfigs(a, b *dbg.GetDebugConfigResponse) []*ConfigDifference {
+	res := []*ConfigDifference{}
+	diffValue := func(field, valueA, valueB string) {
+		if valueA != valueB {
+			res = append(res, &ConfigDifference{Field: field, A: valueA, B: valueB})
+		}
+	}
+	diffMap := func(field string, mapA, mapB map[string]string) {
+		keys := []string{}
+		for k := range mapA {
+			keys = append(keys, k)
+		}
+		for k := range mapB {
+			if _, ok := mapA[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			valueA, inA := mapA[k]
+			valueB, inB := mapB[k]
+			if inA && inB && valueA == valueB {
+				continue
+			}
+			res = append(res, &ConfigDifference{
+				Field:      field,
+				Key:        k,
+				A:          valueA,
+				B:          valueB,
+				MissingInA: !inA,
+				MissingInB: !inB,
+			})
+		}
+	}
+	sortedList := func(list []string) string {
+		list = append([]string{}, list...)
+		sort.Strings(list)
+		return strings.Join(list, " ")
+	}
+	list := func(list []string) string {
+		return strings.Join(list, " ")
+	}
+
+	diffValue("executable", a.GetExecutable(), b.GetExecutable())
+	diffValue("toolchain", a.GetToolchain(), b.GetToolchain())
+	diffValue("toolchain_path", a.GetToolchainPath(), b.GetToolchainPath())
+	diffValue("toolchain_prefix", a.GetToolchainPrefix(), b.GetToolchainPrefix())
+	diffValue("server", a.GetServer(), b.GetServer())
+	diffValue("server_path", a.GetServerPath(), b.GetServerPath())
+	diffMap("toolchain_configuration", a.GetToolchainConfiguration(), b.GetToolchainConfiguration())
+	diffMap("server_configuration", a.GetServerConfiguration(), b.GetServerConfiguration())
+	diffValue("server_working_directory", a.GetServerWorkingDirectory(), b.GetServerWorkingDirectory())
+	diffMap("server_environment", a.GetServerEnvironment(), b.GetServerEnvironment())
+	diffValue("gdb_init_script", a.GetGdbInitScript(), b.GetGdbInitScript())
+	diffValue("missing_tools", sortedList(a.GetMissingTools()), sortedList(b.GetMissingTools()))
+	diffValue("svd_file", a.GetSvdFile(), b.GetSvdFile())
+	diffMap("cortex_debug_configuration", a.GetCortexDebugConfiguration(), b.GetCortexDebugConfiguration())
+	diffValue("cortex_debug_launch_config", a.GetCortexDebugLaunchConfig(), b.GetCortexDebugLaunchConfig())
+	diffMap("raw_debug_properties", a.GetRawDebugProperties(), b.GetRawDebugProperties())
+	diffValue("port_host", a.GetPortHost(), b.GetPortHost())
+	diffValue("port_tcp_port", a.GetPortTcpPort(), b.GetPortTcpPort())
+	diffValue("additional_executables", list(a.GetAdditionalExecutables()), list(b.GetAdditionalExecutables()))
+	diffValue("warnings", sortedList(a.GetWarnings()), sortedList(b.GetWarnings()))
+	return res
+}
@@ -0,0 +1,75 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package debug
+
+import (
+	"testing"
+
+	dbg "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/debug/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestDiffDebugConfigs(t *testing.T) {
+	a := &dbg.GetDebugConfigResponse{
+		Executable:          "/build/sketch.ino.elf",
+		Server:              "openocd",
+		ServerPath:          "/home/a/openocd/bin/openocd",
+		ServerConfiguration: map[string]string{"script": "board.cfg", "scripts_dir": "/home/a/openocd/share"},
+		MissingTools:        []string{"gdb", "openocd"},
+	}
+	b := &dbg.GetDebugConfigResponse{
+		Executable:          "/build/sketch.ino.elf",
+		Server:              "openocd",
+		ServerPath:          "/home/b/openocd/bin/openocd",
+		ServerConfiguration: map[string]string{"script": "board.cfg", "interface": "cmsis-dap"},
+		MissingTools:        []string{"openocd", "gdb"},
+	}
+	require.Empty(t, DiffDebugConfigs(a, a))
+
+	diff := DiffDebugConfigs(a, b)
+	require.Equal(t, []*ConfigDifference{
+		{Field: "server_path", A: "/home/a/openocd/bin/openocd", B: "/home/b/openocd/bin/openocd"},
+		{Field: "server_configuration", Key: "interface", B: "cmsis-dap", MissingInA: true},
+		{Field: "server_configuration", Key: "scripts_dir", A: "/home/a/openocd/share", MissingInB: true},
+	}, diff)
+	require.Equal(t, "server_configuration.interface: (missing) != 'cmsis-dap'", diff[1].String())
+	require.Len(t, DiffDebugConfigs(b, &dbg.GetDebugConfigResponse{}), 6)
+}
+
+func TestDiffDebugConfigsCoversAllFields(t *testing.T) {
+	// Every field of the GetDebugConfigResponse must be compared
+	fields := (&dbg.GetDebugConfigResponse{}).ProtoReflect().Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		b := &dbg.GetDebugConfigResponse{}
+		msg := b.ProtoReflect()
+		value := protoreflect.ValueOfString("value")
+		switch {
+		case field.IsMap():
+			msg.Mutable(field).Map().Set(protoreflect.ValueOfString("key").MapKey(), value)
+		case field.IsList():
+			msg.Mutable(field).List().Append(value)
+		default:
+			require.Equal(t, protoreflect.StringKind, field.Kind(), "field %s", field.Name())
+			msg.Set(field, value)
+		}
+
+		diff := DiffDebugConfigs(&dbg.GetDebugConfigResponse{}, b)
+		require.Len(t, diff, 1, "field %s is not compared", field.Name())
+		require.Equal(t, string(field.Name()), diff[0].Field)
+	}
+}
@@ -17,13 +17,22 @@ package debug
 
 import (
 	"context"
+	"encoding/json"
+	"net"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/arduino/arduino-cli/arduino"
 	"github.com/arduino/arduino-cli/arduino/cores"
 	"github.com/arduino/arduino-cli/arduino/cores/packagemanager"
+	"github.com/arduino/arduino-cli/arduino/discovery"
 	"github.com/arduino/arduino-cli/arduino/sketch"
 	"github.com/arduino/arduino-cli/commands"
+	"github.com/arduino/arduino-cli/legacy/builder/constants"
+	rpc "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/commands/v1"
 	"github.com/arduino/arduino-cli/rpc/cc/arduino/cli/debug/v1"
 	"github.com/arduino/go-paths-helper"
 	"github.com/arduino/go-properties-orderedmap"
@@ -40,86 +49,93 @@ func GetDebugConfig(ctx context.Context, req *debug.DebugConfigRequest) (*debug.
 	return getDebugProperties(req, pme)
 }
 
-func getDebugProperties(req *debug.DebugConfigRequest, pme *packagemanager.Explorer) (*debug.GetDebugConfigResponse, error) {
-	// TODO: make a generic function to extract sketch from request
-	// and remove duplication in commands/compile.go
-	if req.GetSketchPath() == "" {
-		return nil, &arduino.MissingSketchPathError{}
-	}
-	sketchPath := paths.New(req.GetSketchPath())
-	sk, err := sketch.New(sketchPath)
-	if err != nil {
-		return nil, &arduino.CantOpenSketchError{Cause: err}
+// ListDebuggableBoards returns the installed boards, reporting which of them
+// can be debugged. The check uses the same properties used by GetDebugConfig.
+func ListDebuggableBoards(ctx context.Context, req *debug.ListDebuggableBoardsRequest) (*debug.ListDebuggableBoardsResponse, error) {
+	pme, release := commands.GetPackageManagerExplorer(req)
+	if pme == nil {
+		return nil, &arduino.InvalidInstanceError{}
 	}
+	defer release()
+	return listDebuggableBoards(pme), nil
+}
 
-	// XXX Remove this code duplication!!
-	fqbnIn := req.GetFqbn()
-	if fqbnIn == "" && sk != nil {
-		fqbnIn = sk.GetDefaultFQBN()
-	}
-	if fqbnIn == "" {
-		return nil, &arduino.MissingFQBNError{}
-	}
-	fqbn, err := cores.ParseFQBN(fqbnIn)
-	if err != nil {
-		return nil, &arduino.InvalidFQBNError{Cause: err}
-	}
+func listDebuggableBoards(pme *packagemanager.Explorer) *debug.ListDebuggableBoardsResponse {
+	res := &debug.ListDebuggableBoardsResponse{Boards: []*debug.DebuggableBoard{}}
+	for _, targetPackage := range pme.GetPackages() {
+		for _, platform := range targetPackage.Platforms {
+			installedPlatformRelease := pme.GetInstalledPlatformRelease(platform)
+			if installedPlatformRelease == nil {
+				continue
+			}
+			for _, board := range installedPlatformRelease.GetBoards() {
+				debuggableBoard := &debug.DebuggableBoard{
+					Fqbn: board.FQBN(),
+					Name: board.Name(),
+				}
+				res.Boards = append(res.Boards, debuggableBoard)
 
-	// Find target board and board properties
-	_, platformRelease, _, boardProperties, referencedPlatformRelease, err := pme.ResolveFQBN(fqbn)
-	if err != nil {
-		return nil, &arduino.UnknownFQBNError{Cause: err}
+				fqbn, err := cores.ParseFQBN(board.FQBN())
+				if err != nil {
+					continue
+				}
+				toolProperties, err := getDebugToolProperties(pme, fqbn, nil)
+				if err != nil {
+					logrus.WithError(err).WithField("fqbn", board.FQBN()).Warn("Error resolving debug properties")
+					continue
+				}
+				if toolProperties.ContainsKey("debug.executable") {
+					debuggableBoard.DebuggingSupported = true
+					debuggableBoard.Server = toolProperties.ExpandPropsInString(toolProperties.Get("debug.server"))
+				}
+			}
+		}
 	}
+	sort.Slice(res.Boards, func(i, j int) bool {
+		return res.Boards[i].GetFqbn() < res.Boards[j].GetFqbn()
+	})
+	return res
+}
 
-	// Build configuration for debug
-	toolProperties := properties.NewMap()
-	if referencedPlatformRelease != nil {
-		toolProperties.Merge(referencedPlatformRelease.Properties)
+func getDebugProperties(req *debug.DebugConfigRequest, pme *packagemanager.Explorer) (*debug.GetDebugConfigResponse, error) {
+	target, err := commands.ResolveSketchTarget(pme, &commands.SketchTargetRequest{
+		SketchPath: req.GetSketchPath(),
+		Fqbn:       req.GetFqbn(),
+		Programmer: req.GetProgrammer(),
+	})
+	if err != nil {
+		return nil, err
 	}
-	toolProperties.Merge(platformRelease.Properties)
-	toolProperties.Merge(platformRelease.RuntimeProperties())
-	toolProperties.Merge(boardProperties)
+	sk, fqbn, programmerProperties := target.Sketch, target.FQBN, target.ProgrammerProperties
 
-	// HOTFIX: Remove me when the `arduino:samd` core is updated
-	//         (remember to remove it also in arduino/board/details.go)
-	if !toolProperties.ContainsKey("debug.executable") {
-		if platformRelease.String() == "arduino:samd@1.8.9" || platformRelease.String() == "arduino:samd@1.8.8" {
-			toolProperties.Set("debug.executable", "{build.path}/{build.project_name}.elf")
-			toolProperties.Set("debug.toolchain", "gcc")
-			toolProperties.Set("debug.toolchain.path", "{runtime.tools.arm-none-eabi-gcc-7-2017q4.path}/bin/")
-			toolProperties.Set("debug.toolchain.prefix", "arm-none-eabi-")
-			toolProperties.Set("debug.server", "openocd")
-			toolProperties.Set("debug.server.openocd.path", "{runtime.tools.openocd-0.10.0-arduino7.path}/bin/openocd")
-			toolProperties.Set("debug.server.openocd.scripts_dir", "{runtime.tools.openocd-0.10.0-arduino7.path}/share/openocd/scripts/")
-			toolProperties.Set("debug.server.openocd.script", "{runtime.platform.path}/variants/{build.variant}/{build.openocdscript}")
-		}
+	toolProperties, err := getDebugToolProperties(pme, fqbn, programmerProperties)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, tool := range pme.GetAllInstalledToolsReleases() {
-		toolProperties.Merge(tool.RuntimeProperties())
+	// Apply the toolchain overrides requested by the user
+	if toolchainPath := req.GetToolchainPath(); toolchainPath != "" {
+		toolProperties.Set("debug.toolchain.path", toolchainPath)
 	}
-	if requiredTools, err := pme.FindToolsRequiredForBuild(platformRelease, referencedPlatformRelease); err == nil {
-		for _, requiredTool := range requiredTools {
-			logrus.WithField("tool", requiredTool).Info("Tool required for debug")
-			toolProperties.Merge(requiredTool.RuntimeProperties())
-		}
+	if toolchainPrefix := req.GetToolchainPrefix(); toolchainPrefix != "" {
+		toolProperties.Set("debug.toolchain.prefix", toolchainPrefix)
 	}
 
-	if req.GetProgrammer() != "" {
-		if p, ok := platformRelease.Programmers[req.GetProgrammer()]; ok {
-			toolProperties.Merge(p.Properties)
-		} else if refP, ok := referencedPlatformRelease.Programmers[req.GetProgrammer()]; ok {
-			toolProperties.Merge(refP.Properties)
-		} else {
-			return nil, &arduino.ProgrammerNotFoundError{Programmer: req.GetProgrammer()}
+	var executablePath *paths.Path
+	if executable := req.GetExecutable(); executable != "" {
+		executablePath = paths.New(executable)
+		if !executablePath.IsNotDir() {
+			return nil, &arduino.NotFoundError{Message: tr("Executable to debug not found in %s", executablePath)}
 		}
 	}
 
 	var importPath *paths.Path
 	if importDir := req.GetImportDir(); importDir != "" {
 		importPath = paths.New(importDir)
+	} else if executablePath != nil {
+		importPath = executablePath.Parent()
 	} else {
-		importPath = sk.DefaultBuildPath()
+		importPath = sketch.BuildPathFor(sk, fqbn)
 	}
 	if !importPath.Exist() {
 		return nil, &arduino.NotFoundError{Message: tr("Compiled sketch not found in %s", importPath)}
@@ -127,37 +143,348 @@ func getDebugProperties(req *debug.DebugConfigRequest, pme *packagemanager.Explo
 	if !importPath.IsDir() {
 		return nil, &arduino.NotFoundError{Message: tr("Expected compiled sketch in directory %s, but is a file instead", importPath)}
 	}
+	var warnings []string
+	if err := checkBuildFQBN(importPath, fqbn); err != nil {
+		if req.GetStrictFqbnCheck() {
+			return nil, err
+		}
+		logrus.WithError(err).Warn("Compiled sketch may not match the requested board")
+		warnings = append(warnings, err.Error())
+	}
+	projectName := sk.Name + ".ino"
+	if executablePath == nil {
+		if err := checkBuildArtifacts(importPath, projectName); err != nil {
+			return nil, err
+		}
+	}
 	toolProperties.SetPath("build.path", importPath)
-	toolProperties.Set("build.project_name", sk.Name+".ino")
+	toolProperties.Set("build.project_name", projectName)
 
 	// Set debug port property
 	port := req.GetPort()
 	if port.GetAddress() != "" {
 		toolProperties.Set("debug.port", port.Address)
-		portFile := strings.TrimPrefix(port.Address, "/dev/")
-		toolProperties.Set("debug.port.file", portFile)
+		if host, tcpPort, ok := networkPortAddress(port); ok {
+			toolProperties.Set("debug.port.host", host)
+			toolProperties.Set("debug.port.tcp_port", tcpPort)
+		} else {
+			portFile := strings.TrimPrefix(port.Address, "/dev/")
+			toolProperties.Set("debug.port.file", portFile)
+		}
 	}
 
-	// Extract and expand all debugging properties
-	debugProperties := properties.NewMap()
-	for k, v := range toolProperties.SubTree("debug").AsMap() {
-		debugProperties.Set(k, toolProperties.ExpandPropsInString(v))
-	}
+	debugProperties := expandDebugProperties(toolProperties)
 
 	if !debugProperties.ContainsKey("executable") {
 		return nil, &arduino.FailedDebugError{Message: tr("Debugging not supported for board %s", req.GetFqbn())}
 	}
+	if executablePath != nil {
+		// the executable requested by the user replaces the computed one
+		debugProperties.SetPath("executable", executablePath)
+	}
+
+	if req.GetToolchainPath() != "" {
+		if toolchainPath := paths.New(debugProperties.Get("toolchain.path")); !toolchainPath.IsDir() {
+			return nil, &arduino.NotFoundError{Message: tr("Toolchain path %s not found", toolchainPath)}
+		}
+	}
 
 	server := debugProperties.Get("server")
+	if requestedServer := req.GetDebugServer(); requestedServer != "" {
+		availableServers := debugProperties.SubTree("server").FirstLevelKeys()
+		found := false
+		for _, availableServer := range availableServers {
+			if availableServer == requestedServer {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, &arduino.DebugServerNotFoundError{Server: requestedServer, Available: availableServers}
+		}
+		server = requestedServer
+	}
+	// the attach.* properties of the server are used only to attach to a
+	// running program, replacing the normal ones
+	attachPrefix := "server." + server + ".attach."
+	for _, key := range debugProperties.Keys() {
+		if !strings.HasPrefix(key, attachPrefix) {
+			continue
+		}
+		if req.GetAttach() {
+			debugProperties.Set("server."+server+"."+strings.TrimPrefix(key, attachPrefix), debugProperties.Get(key))
+		}
+		debugProperties.Remove(key)
+	}
+
 	toolchain := debugProperties.Get("toolchain")
-	return &debug.GetDebugConfigResponse{
+	res := &debug.GetDebugConfigResponse{
 		Executable:             debugProperties.Get("executable"),
 		Server:                 server,
 		ServerPath:             debugProperties.Get("server." + server + ".path"),
 		ServerConfiguration:    debugProperties.SubTree("server." + server).AsMap(),
+		ServerWorkingDirectory: debugProperties.Get("server." + server + ".cwd"),
+		ServerEnvironment:      debugProperties.SubTree("server." + server + ".env").AsMap(),
 		Toolchain:              toolchain,
 		ToolchainPath:          debugProperties.Get("toolchain.path"),
 		ToolchainPrefix:        debugProperties.Get("toolchain.prefix"),
 		ToolchainConfiguration: debugProperties.SubTree("toolchain." + toolchain).AsMap(),
-	}, nil
+		PortHost:               debugProperties.Get("port.host"),
+		PortTcpPort:            debugProperties.Get("port.tcp_port"),
+		Warnings:               warnings,
+	}
+	if svdFile := debugProperties.Get("svd_file"); svdFile != "" {
+		// relative paths are resolved from the platform folder
+		svdPath := paths.New(svdFile)
+		if !svdPath.IsAbs() {
+			svdPath = paths.New(toolProperties.Get("runtime.platform.path")).JoinPath(svdPath)
+		}
+		res.SvdFile = svdPath.String()
+	}
+	additionalExecutables, err := debugAdditionalExecutables(debugProperties, paths.New(toolProperties.Get("runtime.platform.path")))
+	if err != nil {
+		return nil, err
+	}
+	res.AdditionalExecutables = additionalExecutables
+	if cortexDebugConfiguration := debugProperties.SubTree("cortex-debug"); cortexDebugConfiguration.Size() > 0 {
+		res.CortexDebugConfiguration = cortexDebugConfiguration.AsMap()
+	}
+	if req.GetIncludeRawDebugProperties() {
+		res.RawDebugProperties = toolProperties.SubTree("debug").AsMap()
+	}
+	if req.GetValidatePort() && req.GetPort().GetAddress() != "" {
+		if err := checkDebugPort(req.GetPort(), res, pme.DiscoveryManager().List()); err != nil {
+			return nil, err
+		}
+	}
+	res.MissingTools = missingDebugTools(res)
+	if req.GetGenerateGdbInitScript() {
+		script, err := getGDBInitScript(res, req.GetAttach())
+		if err != nil {
+			return nil, err
+		}
+		res.GdbInitScript = script
+	}
+	if req.GetGenerateCortexDebugLaunchConfig() {
+		launchConfig, err := getCortexDebugLaunchConfig(res, req.GetAttach())
+		if err != nil {
+			return nil, err
+		}
+		res.CortexDebugLaunchConfig = launchConfig
+	}
+	return res, nil
+}
+
+// networkPortAddress returns the host and the TCP port of the address of
+// port, if it's a port of the "network" protocol. The TCP port is empty if
+// the address doesn't include it.
+func networkPortAddress(port *rpc.Port) (host string, tcpPort string, ok bool) {
+	if port.GetProtocol() != "network" {
+		return "", "", false
+	}
+	host, tcpPort, err := net.SplitHostPort(port.GetAddress())
+	if err == nil && host != "" {
+		if n, err := strconv.Atoi(tcpPort); err == nil && n > 0 && n <= 65535 {
+			return host, tcpPort, true
+		}
+	}
+	return port.GetAddress(), "", true
+}
+
+// checkDebugPort checks that port is one of the discovered ports and that
+// its protocol suits the debug server of debugInfo: a server reached through
+// the network (with an address in its configuration) can't be used with a
+// serial port, and a server started locally can't be used with a network
+// port.
+func checkDebugPort(port *rpc.Port, debugInfo *debug.GetDebugConfigResponse, discovered []*discovery.Port) error {
+	protocol := port.GetProtocol()
+	found := false
+	for _, discoveredPort := range discovered {
+		if discoveredPort.Address == port.GetAddress() && (protocol == "" || discoveredPort.Protocol == protocol) {
+			protocol = discoveredPort.Protocol
+			found = true
+			break
+		}
+	}
+	if !found {
+		return &arduino.InvalidArgumentError{Message: tr("Port %s not found", port.GetAddress())}
+	}
+
+	server := debugInfo.GetServer()
+	networkServer := debugInfo.GetServerConfiguration()["address"] != ""
+	if networkServer && protocol == "serial" {
+		return &arduino.FailedDebugError{Message: tr("The debug server %[1]s is reached through the network, it can't be used with the serial port %[2]s", server, port.GetAddress())}
+	}
+	if !networkServer && protocol == "network" {
+		return &arduino.FailedDebugError{Message: tr("The debug server %[1]s is started locally, it can't be used with the network port %[2]s", server, port.GetAddress())}
+	}
+	return nil
+}
+
+// checkBuildFQBN checks, using the build options saved by the compiler in
+// buildPath, that the sketch has been compiled for the board in fqbn. The
+// board options are not compared. If the build options are not available
+// the check is skipped.
+func checkBuildFQBN(buildPath *paths.Path, fqbn *cores.FQBN) error {
+	buildOptionsFile := buildPath.Join(constants.BUILD_OPTIONS_FILE)
+	if buildOptionsFile.NotExist() {
+		return nil
+	}
+	data, err := buildOptionsFile.ReadFile()
+	if err != nil {
+		return &arduino.FailedDebugError{Message: tr("Error reading build options"), Cause: err}
+	}
+	var buildOptions struct {
+		Fqbn string `json:"fqbn"`
+	}
+	if err := json.Unmarshal(data, &buildOptions); err != nil {
+		return &arduino.FailedDebugError{Message: tr("Error reading build options"), Cause: err}
+	}
+	if buildOptions.Fqbn == "" {
+		return nil
+	}
+	buildFQBN, err := cores.ParseFQBN(buildOptions.Fqbn)
+	if err != nil {
+		return &arduino.FailedDebugError{Message: tr("Error reading build options"), Cause: err}
+	}
+	if buildFQBN.StringWithoutConfig() != fqbn.StringWithoutConfig() {
+		return &arduino.FailedDebugError{Message: tr("The sketch in %[1]s has been compiled for %[2]s instead of %[3]s", buildPath, buildFQBN.StringWithoutConfig(), fqbn.StringWithoutConfig())}
+	}
+	return nil
+}
+
+// buildArtifactsExtensions are the extensions of the compiled sketch files
+// looked for by checkBuildArtifacts
+var buildArtifactsExtensions = []string{".elf", ".bin", ".hex"}
+
+// checkBuildArtifacts returns an error if buildPath doesn't contain any of the
+// files produced by the compilation of the sketch projectName, as happens
+// when the import dir points to the build of another sketch.
+func checkBuildArtifacts(buildPath *paths.Path, projectName string) error {
+	artifacts := []string{}
+	for _, ext := range buildArtifactsExtensions {
+		if buildPath.Join(projectName + ext).IsNotDir() {
+			return nil
+		}
+		artifacts = append(artifacts, projectName+ext)
+	}
+	return &arduino.NotFoundError{Message: tr("Compiled sketch not found in %[1]s, expected one of: %[2]s", buildPath, strings.Join(artifacts, ", "))}
+}
+
+// getDebugToolProperties returns the properties of the given board, merged
+// with the properties of its platform, tools and programmer (if not nil), that are used
+// to compute the debug configuration. In case of conflicts the properties
+// are taken, from the highest to the lowest precedence, from:
+//   - the programmer (so a programmer can change the debug.* properties,
+//     for example to use a different debug server)
+//   - the tools
+//   - the board
+//   - the platform
+//   - the referenced platform
+func getDebugToolProperties(pme *packagemanager.Explorer, fqbn *cores.FQBN, programmerProperties *properties.Map) (*properties.Map, error) {
+	// Find target board and board properties
+	_, platformRelease, _, boardProperties, referencedPlatformRelease, err := pme.ResolveFQBN(fqbn)
+	if err != nil {
+		return nil, &arduino.UnknownFQBNError{Cause: err}
+	}
+
+	// Build configuration for debug
+	toolProperties := properties.NewMap()
+	if referencedPlatformRelease != nil {
+		toolProperties.Merge(referencedPlatformRelease.Properties)
+	}
+	toolProperties.Merge(platformRelease.Properties)
+	toolProperties.Merge(platformRelease.RuntimeProperties())
+	toolProperties.Merge(boardProperties)
+
+	// Add the bundled debug properties for the platforms that don't provide them
+	if !toolProperties.ContainsKey("debug.executable") {
+		toolProperties.Merge(GetPlatformDebugOverrides(platformRelease))
+	}
+
+	for _, tool := range pme.GetAllInstalledToolsReleases() {
+		toolProperties.Merge(tool.RuntimeProperties())
+	}
+	if requiredTools, err := pme.FindToolsRequiredForBuild(platformRelease, referencedPlatformRelease); err == nil {
+		for _, requiredTool := range requiredTools {
+			logrus.WithField("tool", requiredTool).Info("Tool required for debug")
+			toolProperties.Merge(requiredTool.RuntimeProperties())
+		}
+	}
+
+	if programmerProperties != nil {
+		toolProperties.Merge(programmerProperties)
+	}
+	return toolProperties, nil
+}
+
+// expandDebugProperties extracts and expands all the debugging properties.
+// ExpandPropsInString repeats the expansion until the value doesn't change
+// anymore (up to a maximum number of passes), so nested placeholders are
+// fully resolved.
+func expandDebugProperties(toolProperties *properties.Map) *properties.Map {
+	debugProperties := properties.NewMap()
+	for k, v := range toolProperties.SubTree("debug").AsMap() {
+		debugProperties.Set(k, toolProperties.ExpandPropsInString(v))
+	}
+	return debugProperties
+}
+
+// debugAdditionalExecutables returns the absolute paths of the executables
+// set in the additional_executables.* debug properties, sorted by their key.
+// The relative paths are resolved from platformPath. An error is returned if
+// one of them can't be found.
+func debugAdditionalExecutables(debugProperties *properties.Map, platformPath *paths.Path) ([]string, error) {
+	additional := debugProperties.SubTree("additional_executables")
+	keys := additional.Keys()
+	sort.Strings(keys)
+	var res []string
+	for _, key := range keys {
+		executable := additional.Get(key)
+		if executable == "" {
+			continue
+		}
+		executablePath := paths.New(executable)
+		if !executablePath.IsAbs() {
+			executablePath = platformPath.JoinPath(executablePath)
+		}
+		if !executablePath.IsNotDir() {
+			return nil, &arduino.NotFoundError{Message: tr("Additional executable to debug not found in %s", executablePath)}
+		}
+		res = append(res, executablePath.String())
+	}
+	return res, nil
+}
+
+// missingDebugTools returns the paths of the GDB and GDB server executables
+// set in debugInfo that can't be found on disk.
+func missingDebugTools(debugInfo *debug.GetDebugConfigResponse) []string {
+	var missing []string
+	if debugInfo.GetToolchain() == "gcc" {
+		gdb := paths.New(debugInfo.GetToolchainPath()).Join(debugInfo.GetToolchainPrefix() + "gdb")
+		if !executableExists(gdb) {
+			missing = append(missing, gdb.String())
+		}
+	}
+	if serverPath := debugInfo.GetServerPath(); serverPath != "" && !executableExists(paths.New(serverPath)) {
+		missing = append(missing, serverPath)
+	}
+	return missing
+}
+
+// executableExists returns true if the executable exists, on Windows the
+// ".exe" suffix is added if missing from the path. Executables given without
+// a directory are searched in the PATH.
+func executableExists(executable *paths.Path) bool {
+	if executable.Base() == executable.String() {
+		_, err := exec.LookPath(executable.String())
+		return err == nil
+	}
+	if executable.IsNotDir() {
+		return true
+	}
+	if runtime.GOOS == "windows" && executable.Ext() != ".exe" {
+		return paths.New(executable.String() + ".exe").IsNotDir()
+	}
+	return false
 }
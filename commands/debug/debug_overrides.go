@@ -0,0 +1,58 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package debug
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"path"
+
+	"github.com/arduino/arduino-cli/arduino/cores"
+	"github.com/arduino/go-properties-orderedmap"
+	"github.com/sirupsen/logrus"
+)
+
+// debugOverrides contains the debug properties for the platform releases
+// that don't provide them. The properties of a platform release are in the
+// file debug_overrides/PACKAGER/ARCHITECTURE/VERSION.txt
+//
+//go:embed debug_overrides
+var debugOverrides embed.FS
+
+// GetPlatformDebugOverrides returns the bundled debug properties for the
+// given platform release, or an empty map if there are none.
+func GetPlatformDebugOverrides(platformRelease *cores.PlatformRelease) *properties.Map {
+	if platformRelease == nil || platformRelease.Version == nil {
+		return properties.NewMap()
+	}
+	platform := platformRelease.Platform
+	overridesFile := path.Join("debug_overrides", platform.Package.Name, platform.Architecture, platformRelease.Version.String()+".txt")
+	data, err := debugOverrides.ReadFile(overridesFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return properties.NewMap()
+	}
+	if err != nil {
+		logrus.WithError(err).Errorf("Error reading debug overrides for %s", platformRelease)
+		return properties.NewMap()
+	}
+	overrides, err := properties.LoadFromBytes(data)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error parsing debug overrides for %s", platformRelease)
+		return properties.NewMap()
+	}
+	return overrides
+}
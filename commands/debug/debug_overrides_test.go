@@ -0,0 +1,57 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package debug
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino/cores"
+	"github.com/arduino/go-properties-orderedmap"
+	"github.com/stretchr/testify/require"
+	semver "go.bug.st/relaxed-semver"
+)
+
+func TestGetPlatformDebugOverrides(t *testing.T) {
+	samd := &cores.Platform{Architecture: "samd", Package: &cores.Package{Name: "arduino"}}
+	release := func(version string) *cores.PlatformRelease {
+		return &cores.PlatformRelease{Platform: samd, Version: semver.MustParse(version)}
+	}
+
+	for _, version := range []string{"1.8.8", "1.8.9"} {
+		overrides := GetPlatformDebugOverrides(release(version))
+		require.Equal(t, "{build.path}/{build.project_name}.elf", overrides.Get("debug.executable"))
+		require.Equal(t, "openocd", overrides.Get("debug.server"))
+	}
+	require.Equal(t, 0, GetPlatformDebugOverrides(release("1.8.10")).Size())
+	require.Equal(t, 0, GetPlatformDebugOverrides(nil).Size())
+}
+
+func TestDebugOverridesAreValid(t *testing.T) {
+	err := fs.WalkDir(debugOverrides, "debug_overrides", func(path string, d fs.DirEntry, err error) error {
+		require.NoError(t, err)
+		if d.IsDir() {
+			return nil
+		}
+		data, err := debugOverrides.ReadFile(path)
+		require.NoError(t, err)
+		overrides, err := properties.LoadFromBytes(data)
+		require.NoError(t, err, path)
+		require.True(t, overrides.ContainsKey("debug.executable"), path)
+		return nil
+	})
+	require.NoError(t, err)
+}
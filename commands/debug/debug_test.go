@@ -15,16 +15,20 @@
 package debug
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/arduino/arduino-cli/arduino"
 	"github.com/arduino/arduino-cli/arduino/cores/packagemanager"
+	"github.com/arduino/arduino-cli/arduino/discovery"
 	rpc "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/commands/v1"
 	dbg "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/debug/v1"
 	"github.com/arduino/go-paths-helper"
+	"github.com/arduino/go-properties-orderedmap"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -91,3 +95,654 @@ func TestGetCommandLine(t *testing.T) {
 	commandToTest2 := strings.Join(command2[:], " ")
 	assert.Equal(t, filepath.FromSlash(goldCommand2), filepath.FromSlash(commandToTest2))
 }
+
+// newTestDebugConfigRequest returns a package manager explorer loaded with the
+// test hardware and a request to debug the hello sketch on an Arduino Zero
+func newTestDebugConfigRequest(t *testing.T) (*packagemanager.Explorer, func(), *dbg.DebugConfigRequest) {
+	customHardware := paths.New("testdata", "custom_hardware")
+	dataDir := paths.New("testdata", "data_dir", "packages")
+	sketchPath := paths.New("testdata", "hello")
+	require.NoError(t, sketchPath.ToAbs())
+
+	pmb := packagemanager.NewBuilder(nil, nil, nil, nil, "test")
+	pmb.LoadHardwareFromDirectory(customHardware)
+	pmb.LoadHardwareFromDirectory(dataDir)
+	pm := pmb.Build()
+	pme, release := pm.NewExplorer()
+
+	req := &dbg.DebugConfigRequest{
+		Instance:   &rpc.Instance{Id: 1},
+		Fqbn:       "arduino-test:samd:arduino_zero_edbg",
+		SketchPath: sketchPath.String(),
+		ImportDir:  sketchPath.Join("build", "arduino-test.samd.arduino_zero_edbg").String(),
+	}
+	return pme, release, req
+}
+
+func TestGetDebugPropertiesWithDebugServer(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	// The default server is used if none is requested
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, "openocd", res.GetServer())
+
+	req.DebugServer = "jlink"
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, "jlink", res.GetServer())
+	require.Equal(t, "JLinkGDBServer", res.GetServerPath())
+	require.Equal(t, map[string]string{"path": "JLinkGDBServer", "device": "ATSAMD21G18"}, res.GetServerConfiguration())
+
+	req.DebugServer = "stlink"
+	_, err = getDebugProperties(req, pme)
+	var serverErr *arduino.DebugServerNotFoundError
+	require.ErrorAs(t, err, &serverErr)
+	require.ElementsMatch(t, []string{"openocd", "jlink"}, serverErr.Available)
+	require.Contains(t, err.Error(), "openocd")
+}
+
+func TestGetDebugPropertiesWithToolchainOverrides(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	toolchainPath := paths.New("testdata", "data_dir", "packages", "arduino-test", "tools", "arm-none-eabi-gcc", "7-2017q4")
+	require.NoError(t, toolchainPath.ToAbs())
+	req.ToolchainPath = toolchainPath.String()
+	req.ToolchainPrefix = "arm-custom-eabi-"
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, toolchainPath.String(), res.GetToolchainPath())
+	require.Equal(t, "arm-custom-eabi-", res.GetToolchainPrefix())
+
+	req.ToolchainPath = toolchainPath.Join("missing").String()
+	_, err = getDebugProperties(req, pme)
+	var notFoundErr *arduino.NotFoundError
+	require.ErrorAs(t, err, &notFoundErr)
+	require.Contains(t, err.Error(), "missing")
+}
+
+func TestGetDebugPropertiesExpandsNestedPlaceholders(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	req.Fqbn = "arduino-test:samd:arduino_zero_custom_dir"
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	platformPath := paths.New("testdata", "custom_hardware", "arduino-test", "samd")
+	require.NoError(t, platformPath.ToAbs())
+	require.Equal(t,
+		filepath.FromSlash(platformPath.String()+"/custom/openocd_scripts/arduino_zero.cfg"),
+		filepath.FromSlash(res.GetServerConfiguration()["script"]))
+}
+
+func TestGetDebugPropertiesServerWorkingDirectory(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	// No working directory is required by default
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetServerWorkingDirectory())
+
+	req.Fqbn = "arduino-test:samd:arduino_zero_custom_dir"
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	platformPath := paths.New("testdata", "custom_hardware", "arduino-test", "samd")
+	require.NoError(t, platformPath.ToAbs())
+	require.Equal(t,
+		filepath.FromSlash(platformPath.String()+"/custom"),
+		filepath.FromSlash(res.GetServerWorkingDirectory()))
+}
+
+func TestGetDebugPropertiesSvdFile(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	// The board doesn't provide an SVD file
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetSvdFile())
+	require.Empty(t, res.GetCortexDebugConfiguration())
+
+	// Relative paths are resolved from the platform folder
+	req.Fqbn = "arduino-test:samd:arduino_zero_custom_dir"
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	platformPath := paths.New("testdata", "custom_hardware", "arduino-test", "samd")
+	require.NoError(t, platformPath.ToAbs())
+	require.Equal(t, platformPath.Join("svd", "ATSAMD21G18A.svd").String(), res.GetSvdFile())
+	require.Equal(t, map[string]string{"custom.rtos": "FreeRTOS"}, res.GetCortexDebugConfiguration())
+}
+
+func TestGetDebugPropertiesAdditionalExecutables(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+	req.GenerateGdbInitScript = true
+
+	// The board doesn't provide additional executables
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetAdditionalExecutables())
+	require.NotContains(t, res.GetGdbInitScript(), "add-symbol-file")
+
+	// Relative paths are resolved from the platform folder
+	req.Fqbn = "arduino-test:samd:arduino_zero_custom_dir"
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	platformPath := paths.New("testdata", "custom_hardware", "arduino-test", "samd")
+	require.NoError(t, platformPath.ToAbs())
+	bootloader := platformPath.Join("bootloaders", "zero", "samd21_sam_ba.elf").String()
+	require.Equal(t, []string{bootloader}, res.GetAdditionalExecutables())
+	require.Contains(t, res.GetGdbInitScript(), fmt.Sprintf("add-symbol-file \"%s\"\n", filepath.ToSlash(bootloader)))
+}
+
+func TestDebugAdditionalExecutables(t *testing.T) {
+	platformPath := paths.New("testdata", "custom_hardware", "arduino-test", "samd")
+	require.NoError(t, platformPath.ToAbs())
+	bootloader := platformPath.Join("bootloaders", "zero", "samd21_sam_ba.elf")
+
+	res, err := debugAdditionalExecutables(properties.NewMap(), platformPath)
+	require.NoError(t, err)
+	require.Empty(t, res)
+
+	// The executables are sorted by key, the absolute paths are kept
+	debugProperties := properties.NewMap()
+	debugProperties.Set("additional_executables.1", "bootloaders/zero/samd21_sam_ba.elf")
+	debugProperties.Set("additional_executables.0", bootloader.String())
+	res, err = debugAdditionalExecutables(debugProperties, platformPath)
+	require.NoError(t, err)
+	require.Equal(t, []string{bootloader.String(), bootloader.String()}, res)
+
+	// The missing executables are reported
+	debugProperties.Set("additional_executables.2", "bootloaders/missing.elf")
+	_, err = debugAdditionalExecutables(debugProperties, platformPath)
+	var notFound *arduino.NotFoundError
+	require.ErrorAs(t, err, &notFound)
+	require.Contains(t, err.Error(), "missing.elf")
+}
+
+func TestGetDebugPropertiesAttach(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+	req.Fqbn = "arduino-test:samd:arduino_zero_custom_dir"
+	req.GenerateGdbInitScript = true
+	platformPath := paths.New("testdata", "custom_hardware", "arduino-test", "samd")
+	require.NoError(t, platformPath.ToAbs())
+
+	// The attach properties are not used by default
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t,
+		filepath.FromSlash(platformPath.String()+"/custom/openocd_scripts/arduino_zero.cfg"),
+		filepath.FromSlash(res.GetServerConfiguration()["script"]))
+	require.NotContains(t, res.GetServerConfiguration(), "attach.script")
+	require.Contains(t, res.GetGdbInitScript(), "load\n")
+
+	req.Attach = true
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t,
+		filepath.FromSlash(platformPath.String()+"/custom/attach.cfg"),
+		filepath.FromSlash(res.GetServerConfiguration()["script"]))
+	require.NotContains(t, res.GetServerConfiguration(), "attach.script")
+	require.NotContains(t, res.GetGdbInitScript(), "load\n")
+	require.NotContains(t, res.GetGdbInitScript(), "monitor reset\n")
+
+	// Without attach properties the normal configuration is used
+	req.Fqbn = "arduino-test:samd:arduino_zero_edbg"
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t,
+		filepath.FromSlash(platformPath.String()+"/variants/arduino_zero/openocd_scripts/arduino_zero.cfg"),
+		filepath.FromSlash(res.GetServerConfiguration()["script"]))
+}
+
+func TestGetDebugPropertiesServerEnvironment(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetServerEnvironment())
+
+	req.Fqbn = "arduino-test:samd:arduino_zero_custom_dir"
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	dataDir := paths.New("testdata", "data_dir", "packages")
+	require.NoError(t, dataDir.ToAbs())
+	env := res.GetServerEnvironment()
+	require.Len(t, env, 2)
+	require.Equal(t,
+		filepath.FromSlash(dataDir.String()+"/arduino-test/tools/openocd/0.10.0-arduino7/share/openocd/scripts"),
+		filepath.FromSlash(env["OPENOCD_SCRIPTS"]))
+	require.Equal(t,
+		filepath.FromSlash(dataDir.String()+"/arduino-test/tools/arm-none-eabi-gcc/7-2017q4/bin"),
+		filepath.FromSlash(env["GDB_PATH"]))
+}
+
+func TestListDebuggableBoards(t *testing.T) {
+	pme, release, _ := newTestDebugConfigRequest(t)
+	defer release()
+
+	res := listDebuggableBoards(pme)
+	boards := map[string]*dbg.DebuggableBoard{}
+	fqbns := []string{}
+	for _, board := range res.GetBoards() {
+		boards[board.GetFqbn()] = board
+		fqbns = append(fqbns, board.GetFqbn())
+	}
+	require.Equal(t, []string{
+		"arduino-test:avr:uno",
+		"arduino-test:samd:arduino_zero_custom_dir",
+		"arduino-test:samd:arduino_zero_edbg",
+		"arduino-test:samd:mkr1000",
+		"arduino-test:samd:tian",
+	}, fqbns)
+
+	require.True(t, boards["arduino-test:samd:arduino_zero_edbg"].GetDebuggingSupported())
+	require.Equal(t, "openocd", boards["arduino-test:samd:arduino_zero_edbg"].GetServer())
+	require.False(t, boards["arduino-test:avr:uno"].GetDebuggingSupported())
+	require.Equal(t, "", boards["arduino-test:avr:uno"].GetServer())
+}
+
+func TestGetDebugPropertiesMissingTools(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	// The tools are installed in the test data dir
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetMissingTools())
+
+	// The toolchain doesn't contain the GDB with the requested prefix
+	req.ToolchainPrefix = "arm-custom-eabi-"
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Len(t, res.GetMissingTools(), 1)
+	require.Equal(t, "arm-custom-eabi-gdb", paths.New(res.GetMissingTools()[0]).Base())
+
+	// JLinkGDBServer is searched in the PATH
+	req.ToolchainPrefix = ""
+	req.DebugServer = "jlink"
+	t.Setenv("PATH", t.TempDir())
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, []string{"JLinkGDBServer"}, res.GetMissingTools())
+}
+
+func TestGetDebugPropertiesWithReferencedPlatformProgrammer(t *testing.T) {
+	pmb := packagemanager.NewBuilder(nil, nil, nil, nil, "test")
+	pmb.LoadHardwareFromDirectory(paths.New("testdata", "custom_hardware"))
+	pmb.LoadHardwareFromDirectory(paths.New("testdata", "referencing_hardware"))
+	pmb.LoadHardwareFromDirectory(paths.New("testdata", "data_dir", "packages"))
+	pm := pmb.Build()
+	pme, release := pm.NewExplorer()
+	defer release()
+	sketchPath := paths.New("testdata", "hello")
+	require.NoError(t, sketchPath.ToAbs())
+
+	// The programmer is defined in the platform referenced by the board
+	req := &dbg.DebugConfigRequest{
+		Instance:   &rpc.Instance{Id: 1},
+		Fqbn:       "referencing-test:samd:ref_zero",
+		SketchPath: sketchPath.String(),
+		ImportDir:  sketchPath.Join("build", "referencing-test.samd.ref_zero").String(),
+		Programmer: "jlink",
+	}
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, "jlink", res.GetServer())
+	require.Equal(t, "JLinkGDBServerCL", res.GetServerPath())
+
+	req.Programmer = "atmel_ice"
+	_, err = getDebugProperties(req, pme)
+	var programmerErr *arduino.ProgrammerNotFoundError
+	require.ErrorAs(t, err, &programmerErr)
+	require.Equal(t, "atmel_ice", programmerErr.Programmer)
+}
+
+func TestGetAvailableDebugTools(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	res, err := getAvailableDebugTools(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, "openocd", res.DefaultServer)
+	require.Len(t, res.Servers, 2)
+	require.Equal(t, "jlink", res.Servers[0].Name)
+	require.Equal(t, "JLinkGDBServer", res.Servers[0].Path)
+	require.Equal(t, "ATSAMD21G18", res.Servers[0].Configuration["device"])
+	require.Equal(t, "openocd", res.Servers[1].Name)
+	require.Equal(t, "openocd", paths.New(res.Servers[1].Path).Base())
+
+	// The server paths match the ones returned by GetDebugConfig
+	config, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, config.GetServerPath(), res.Servers[1].Path)
+
+	require.Equal(t, "gcc", res.DefaultToolchain)
+	require.Len(t, res.Toolchains, 1)
+	require.Equal(t, "gcc", res.Toolchains[0].Name)
+	require.Equal(t, config.GetToolchainPath(), res.Toolchains[0].Path)
+	require.Equal(t, "arm-none-eabi-", res.Toolchains[0].Prefix)
+
+	req.Fqbn = ""
+	_, err = getAvailableDebugTools(req, pme)
+	var missingFQBNErr *arduino.MissingFQBNError
+	require.ErrorAs(t, err, &missingFQBNErr)
+}
+
+func TestGetDebugPropertiesExecutableOverride(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	executable := paths.New(req.GetImportDir()).Join("hello.ino.bin")
+	req.Executable = executable.String()
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, executable.String(), res.GetExecutable())
+
+	// The import dir defaults to the folder of the executable
+	req.ImportDir = ""
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, executable.String(), res.GetExecutable())
+
+	req.Executable = executable.Parent().Join("missing.elf").String()
+	_, err = getDebugProperties(req, pme)
+	require.ErrorAs(t, err, new(*arduino.NotFoundError))
+
+	req.Executable = executable.Parent().String()
+	_, err = getDebugProperties(req, pme)
+	require.ErrorAs(t, err, new(*arduino.NotFoundError))
+}
+
+func TestGetDebugPropertiesWithProgrammerOverrides(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	req.Programmer = "jlink"
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, "jlink", res.GetServer())
+	require.Equal(t, "JLinkGDBServerCL", res.GetServerPath())
+	// Properties not set by the programmer are taken from the platform
+	require.Equal(t, "ATSAMD21G18", res.GetServerConfiguration()["device"])
+
+	req.Programmer = "not-existent"
+	_, err = getDebugProperties(req, pme)
+	var programmerErr *arduino.ProgrammerNotFoundError
+	require.ErrorAs(t, err, &programmerErr)
+}
+
+func TestGetDebugPropertiesUnknownProgrammerOnSinglePlatformBoard(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	req.Fqbn = "arduino-test:avr:uno"
+	req.Programmer = "not-existent"
+	var res *dbg.GetDebugConfigResponse
+	var err error
+	require.NotPanics(t, func() { res, err = getDebugProperties(req, pme) })
+	require.Nil(t, res)
+	var programmerErr *arduino.ProgrammerNotFoundError
+	require.ErrorAs(t, err, &programmerErr)
+	require.Equal(t, "not-existent", programmerErr.Programmer)
+}
+
+func TestGetDebugPropertiesChecksBuildArtifacts(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	importDir := paths.New(t.TempDir())
+	req.ImportDir = importDir.String()
+	require.NoError(t, importDir.Join("other.ino.elf").WriteFile([]byte{}))
+	_, err := getDebugProperties(req, pme)
+	require.ErrorAs(t, err, new(*arduino.NotFoundError))
+	require.Contains(t, err.Error(), "hello.ino.elf")
+
+	require.NoError(t, importDir.Join("hello.ino.elf").WriteFile([]byte{}))
+	_, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+
+	// The check is skipped if the executable is given
+	req.Executable = importDir.Join("other.ino.elf").String()
+	_, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+}
+
+func TestGetDebugPropertiesChecksBuildFQBN(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	importDir := paths.New(t.TempDir())
+	req.ImportDir = importDir.String()
+	require.NoError(t, importDir.Join("hello.ino.bin").WriteFile([]byte{}))
+
+	// Without build options the check is skipped
+	req.StrictFqbnCheck = true
+	_, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+
+	// The board options are not compared
+	buildOptions := importDir.Join("build.options.json")
+	require.NoError(t, buildOptions.WriteFile([]byte(`{"fqbn": "arduino-test:samd:arduino_zero_edbg:opt=val"}`)))
+	_, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+
+	require.NoError(t, buildOptions.WriteFile([]byte(`{"fqbn": "arduino-test:samd:mkr1000"}`)))
+	_, err = getDebugProperties(req, pme)
+	var debugErr *arduino.FailedDebugError
+	require.ErrorAs(t, err, &debugErr)
+	require.Contains(t, err.Error(), "arduino-test:samd:mkr1000")
+
+	// If the check is not strict the mismatch is reported as a warning
+	req.StrictFqbnCheck = false
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Len(t, res.GetWarnings(), 1)
+	require.Contains(t, res.GetWarnings()[0], "arduino-test:samd:mkr1000")
+}
+
+func TestGetDebugPropertiesGDBInitScript(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	// The script is generated only if requested
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetGdbInitScript())
+
+	req.GenerateGdbInitScript = true
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t,
+		"file \""+filepath.ToSlash(res.GetExecutable())+"\"\n"+
+			filepath.ToSlash(openocdTargetCommand(res))+"\n"+
+			"load\n"+
+			"monitor reset\n",
+		res.GetGdbInitScript())
+
+	// Other servers are reached through their address
+	req.Programmer = "jlink"
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t,
+		"file \""+filepath.ToSlash(res.GetExecutable())+"\"\n"+
+			"target extended-remote localhost:2331\n"+
+			"load\n"+
+			"monitor reset\n",
+		res.GetGdbInitScript())
+
+	// The script can't be generated if the address is unknown
+	req.Programmer = ""
+	req.DebugServer = "jlink"
+	_, err = getDebugProperties(req, pme)
+	var debugErr *arduino.FailedDebugError
+	require.ErrorAs(t, err, &debugErr)
+	require.Contains(t, err.Error(), "jlink")
+}
+
+func TestGetDebugPropertiesCortexDebugLaunchConfig(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	// The launch configuration is generated only if requested
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetCortexDebugLaunchConfig())
+
+	// The optional fields are omitted if the board doesn't provide them
+	req.GenerateCortexDebugLaunchConfig = true
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(res.GetCortexDebugLaunchConfig()), &config))
+	require.Equal(t, "cortex-debug", config["type"])
+	require.Equal(t, "launch", config["request"])
+	require.Equal(t, "openocd", config["servertype"])
+	require.Equal(t, filepath.ToSlash(res.GetExecutable()), config["executable"])
+	require.Equal(t, filepath.ToSlash(res.GetServerPath()), config["serverpath"])
+	require.Equal(t, strings.TrimSuffix(res.GetToolchainPrefix(), "-"), config["toolchainPrefix"])
+	require.NotContains(t, config, "svdFile")
+	require.NotContains(t, config, "rtos")
+
+	// The SVD file and the cortex-debug properties of the board are added
+	req.Fqbn = "arduino-test:samd:arduino_zero_custom_dir"
+	req.Attach = true
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	config = nil
+	require.NoError(t, json.Unmarshal([]byte(res.GetCortexDebugLaunchConfig()), &config))
+	require.Equal(t, "attach", config["request"])
+	require.Equal(t, filepath.ToSlash(res.GetSvdFile()), config["svdFile"])
+	require.Equal(t, "FreeRTOS", config["rtos"])
+	require.Equal(t, []interface{}{filepath.ToSlash(res.GetServerConfiguration()["script"])}, config["configFiles"])
+}
+
+func TestGetDebugPropertiesValidatePort(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+	req.Port = &rpc.Port{Address: "/dev/ttyACM0", Protocol: "serial"}
+
+	// The port is not checked by default
+	_, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+
+	req.ValidatePort = true
+	_, err = getDebugProperties(req, pme)
+	var argErr *arduino.InvalidArgumentError
+	require.ErrorAs(t, err, &argErr)
+	require.Contains(t, err.Error(), "/dev/ttyACM0")
+}
+
+func TestCheckDebugPort(t *testing.T) {
+	discovered := []*discovery.Port{
+		{Address: "/dev/ttyACM0", Protocol: "serial"},
+		{Address: "192.168.1.10", Protocol: "network"},
+	}
+	localServer := &dbg.GetDebugConfigResponse{Server: "openocd", ServerConfiguration: map[string]string{"script": "board.cfg"}}
+	networkServer := &dbg.GetDebugConfigResponse{Server: "jlink", ServerConfiguration: map[string]string{"address": "localhost:2331"}}
+
+	// The protocol is taken from the discovered port if missing
+	require.NoError(t, checkDebugPort(&rpc.Port{Address: "/dev/ttyACM0"}, localServer, discovered))
+	require.NoError(t, checkDebugPort(&rpc.Port{Address: "192.168.1.10"}, networkServer, discovered))
+	require.NoError(t, checkDebugPort(&rpc.Port{Address: "192.168.1.10", Protocol: "network"}, networkServer, discovered))
+
+	var argErr *arduino.InvalidArgumentError
+	require.ErrorAs(t, checkDebugPort(&rpc.Port{Address: "/dev/ttyUSB0"}, localServer, discovered), &argErr)
+	require.ErrorAs(t, checkDebugPort(&rpc.Port{Address: "/dev/ttyACM0", Protocol: "network"}, localServer, discovered), &argErr)
+
+	// The protocol must suit the server
+	var debugErr *arduino.FailedDebugError
+	err := checkDebugPort(&rpc.Port{Address: "/dev/ttyACM0"}, networkServer, discovered)
+	require.ErrorAs(t, err, &debugErr)
+	require.Contains(t, err.Error(), "jlink")
+	err = checkDebugPort(&rpc.Port{Address: "192.168.1.10"}, localServer, discovered)
+	require.ErrorAs(t, err, &debugErr)
+	require.Contains(t, err.Error(), "openocd")
+}
+
+func TestGetDebugPropertiesRawDebugProperties(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+
+	// The raw properties are returned only if requested
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetRawDebugProperties())
+
+	req.IncludeRawDebugProperties = true
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	raw := res.GetRawDebugProperties()
+	require.Contains(t, raw, "executable")
+	require.Contains(t, raw["executable"], "{build.path}")
+	require.NotEqual(t, res.GetExecutable(), raw["executable"])
+	require.Equal(t, res.GetServer(), raw["server"])
+}
+
+func TestGetDebugPropertiesNetworkPort(t *testing.T) {
+	pme, release, req := newTestDebugConfigRequest(t)
+	defer release()
+	req.IncludeRawDebugProperties = true
+
+	// The serial ports set the port file
+	req.Port = &rpc.Port{Address: "/dev/ttyACM0", Protocol: "serial"}
+	res, err := getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetPortHost())
+	require.Empty(t, res.GetPortTcpPort())
+	require.Equal(t, "ttyACM0", res.GetRawDebugProperties()["port.file"])
+
+	// The network ports set the host and the TCP port instead
+	req.Port = &rpc.Port{Address: "192.168.1.10:3333", Protocol: "network"}
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, "192.168.1.10", res.GetPortHost())
+	require.Equal(t, "3333", res.GetPortTcpPort())
+	require.NotContains(t, res.GetRawDebugProperties(), "port.file")
+
+	// The ports without a protocol are not network ports, even if their
+	// address looks like a host:port
+	req.Port = &rpc.Port{Address: "192.168.1.10:3333"}
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Empty(t, res.GetPortHost())
+	require.Empty(t, res.GetPortTcpPort())
+	require.Equal(t, "192.168.1.10:3333", res.GetRawDebugProperties()["port.file"])
+
+	// The TCP port is optional for the network ports
+	req.Port = &rpc.Port{Address: "board.local", Protocol: "network"}
+	res, err = getDebugProperties(req, pme)
+	require.NoError(t, err)
+	require.Equal(t, "board.local", res.GetPortHost())
+	require.Empty(t, res.GetPortTcpPort())
+}
+
+func TestNetworkPortAddress(t *testing.T) {
+	tests := []struct {
+		port    *rpc.Port
+		host    string
+		tcpPort string
+		ok      bool
+	}{
+		{&rpc.Port{Address: "/dev/ttyACM0"}, "", "", false},
+		{&rpc.Port{Address: "COM3"}, "", "", false},
+		{&rpc.Port{Address: "/dev/ttyACM0", Protocol: "serial"}, "", "", false},
+		{&rpc.Port{Address: "localhost:3333"}, "", "", false},
+		{&rpc.Port{Address: "localhost:3333", Protocol: "serial"}, "", "", false},
+		{&rpc.Port{Address: "localhost:3333", Protocol: "network"}, "localhost", "3333", true},
+		{&rpc.Port{Address: "[::1]:3333", Protocol: "network"}, "::1", "3333", true},
+		{&rpc.Port{Address: "localhost:gdb", Protocol: "network"}, "localhost:gdb", "", true},
+		{&rpc.Port{Address: "10.0.0.2", Protocol: "network"}, "10.0.0.2", "", true},
+	}
+	for _, test := range tests {
+		host, tcpPort, ok := networkPortAddress(test.port)
+		require.Equal(t, test.ok, ok, test.port.GetAddress())
+		require.Equal(t, test.host, host, test.port.GetAddress())
+		require.Equal(t, test.tcpPort, tcpPort, test.port.GetAddress())
+	}
+}
@@ -0,0 +1,130 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package debug
+
+import (
+	"context"
+	"sort"
+
+	"github.com/arduino/arduino-cli/arduino"
+	"github.com/arduino/arduino-cli/arduino/cores"
+	"github.com/arduino/arduino-cli/arduino/cores/packagemanager"
+	"github.com/arduino/arduino-cli/commands"
+	"github.com/arduino/arduino-cli/rpc/cc/arduino/cli/debug/v1"
+	"github.com/arduino/go-properties-orderedmap"
+)
+
+// DebugServer is a GDB server declared by a board
+type DebugServer struct {
+	Name          string
+	Path          string
+	Configuration map[string]string
+}
+
+// DebugToolchain is a debug toolchain declared by a board
+type DebugToolchain struct {
+	Name          string
+	Path          string
+	Prefix        string
+	Configuration map[string]string
+}
+
+// AvailableDebugTools lists the GDB servers and the toolchains that may be
+// used to debug a board, sorted by name.
+type AvailableDebugTools struct {
+	DefaultServer    string
+	Servers          []*DebugServer
+	DefaultToolchain string
+	Toolchains       []*DebugToolchain
+}
+
+// GetAvailableDebugTools returns all the GDB servers and toolchains declared
+// by the board set in req (debug.server.* and debug.toolchain.* properties),
+// so that the user may choose among them. The properties are resolved in the
+// same way as GetDebugConfig does, the programmer set in req is taken into
+// account. The sketch and the build path set in req are not used.
+func GetAvailableDebugTools(ctx context.Context, req *debug.DebugConfigRequest) (*AvailableDebugTools, error) {
+	pme, release := commands.GetPackageManagerExplorer(req)
+	if pme == nil {
+		return nil, &arduino.InvalidInstanceError{}
+	}
+	defer release()
+	return getAvailableDebugTools(req, pme)
+}
+
+func getAvailableDebugTools(req *debug.DebugConfigRequest, pme *packagemanager.Explorer) (*AvailableDebugTools, error) {
+	if req.GetFqbn() == "" {
+		return nil, &arduino.MissingFQBNError{}
+	}
+	fqbn, err := cores.ParseFQBN(req.GetFqbn())
+	if err != nil {
+		return nil, &arduino.InvalidFQBNError{Cause: err}
+	}
+	var programmerProperties *properties.Map
+	if programmer := req.GetProgrammer(); programmer != "" {
+		_, platformRelease, _, _, referencedPlatformRelease, err := pme.ResolveFQBN(fqbn)
+		if err != nil {
+			return nil, &arduino.UnknownFQBNError{Cause: err}
+		}
+		_, p, err := commands.ResolveProgrammer(programmer, platformRelease, referencedPlatformRelease)
+		if err != nil {
+			return nil, err
+		}
+		programmerProperties = p.Properties
+	}
+	toolProperties, err := getDebugToolProperties(pme, fqbn, programmerProperties)
+	if err != nil {
+		return nil, err
+	}
+	debugProperties := expandDebugProperties(toolProperties)
+
+	res := &AvailableDebugTools{
+		DefaultServer:    debugProperties.Get("server"),
+		DefaultToolchain: debugProperties.Get("toolchain"),
+	}
+	servers := debugProperties.SubTree("server")
+	for _, name := range servers.FirstLevelKeys() {
+		res.Servers = append(res.Servers, &DebugServer{
+			Name:          name,
+			Path:          servers.Get(name + ".path"),
+			Configuration: servers.SubTree(name).AsMap(),
+		})
+	}
+	sort.Slice(res.Servers, func(i, j int) bool { return res.Servers[i].Name < res.Servers[j].Name })
+
+	// The toolchain path and prefix are shared by all the toolchains, the
+	// other keys are the toolchains configurations
+	toolchains := debugProperties.SubTree("toolchain")
+	names := map[string]bool{}
+	if res.DefaultToolchain != "" {
+		names[res.DefaultToolchain] = true
+	}
+	for _, name := range toolchains.FirstLevelKeys() {
+		if name != "path" && name != "prefix" {
+			names[name] = true
+		}
+	}
+	for name := range names {
+		res.Toolchains = append(res.Toolchains, &DebugToolchain{
+			Name:          name,
+			Path:          toolchains.Get("path"),
+			Prefix:        toolchains.Get("prefix"),
+			Configuration: toolchains.SubTree(name).AsMap(),
+		})
+	}
+	sort.Slice(res.Toolchains, func(i, j int) bool { return res.Toolchains[i].Name < res.Toolchains[j].Name })
+	return res, nil
+}
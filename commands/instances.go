@@ -567,7 +567,11 @@ func UpdateIndex(ctx context.Context, req *rpc.UpdateIndexRequest, downloadCB rp
 // LoadSketch collects and returns all files composing a sketch
 func LoadSketch(ctx context.Context, req *rpc.LoadSketchRequest) (*rpc.LoadSketchResponse, error) {
 	// TODO: This should be a ToRpc function for the Sketch struct
-	sk, err := sketch.New(paths.New(req.SketchPath))
+	sketchPath, err := resolveSketchPath(req.SketchPath, configuration.SketchbookDirs(configuration.Settings))
+	if err != nil {
+		return nil, &arduino.CantOpenSketchError{Cause: err}
+	}
+	sk, err := sketch.New(sketchPath)
 	if err != nil {
 		return nil, &arduino.CantOpenSketchError{Cause: err}
 	}
@@ -587,11 +591,38 @@ func LoadSketch(ctx context.Context, req *rpc.LoadSketchRequest) (*rpc.LoadSketc
 		rootFolderFiles[i] = file.String()
 	}
 
+	defaultPort, defaultProtocol := sk.GetDefaultPortAddressAndProtocol()
 	return &rpc.LoadSketchResponse{
 		MainFile:         sk.MainFile.String(),
 		LocationPath:     sk.FullPath.String(),
 		OtherSketchFiles: otherSketchFiles,
 		AdditionalFiles:  additionalFiles,
 		RootFolderFiles:  rootFolderFiles,
+		DefaultFqbn:      sk.GetDefaultFQBN(),
+		DefaultPort:      defaultPort,
+		DefaultProtocol:  defaultProtocol,
 	}, nil
 }
+
+// resolveSketchPath returns the path of the sketch to load. The sketch path
+// may be an absolute path or a path relative to the current directory; if
+// it's a bare sketch name that doesn't exist in the current directory it's
+// searched in the sketchbooks, in order, and the first match is returned. If
+// none of the sketchbooks contains the sketch an error matching
+// sketch.ErrSketchNotFound is returned.
+func resolveSketchPath(sketchPath string, sketchbookDirs paths.PathList) (*paths.Path, error) {
+	path := paths.New(sketchPath)
+	if path == nil || path.IsAbs() || path.Exist() {
+		return path, nil
+	}
+	if len(sketchbookDirs) == 0 || filepath.Base(sketchPath) != sketchPath {
+		return path, nil
+	}
+	for _, sketchbookDir := range sketchbookDirs {
+		if sketchbookPath := sketchbookDir.Join(sketchPath); sketchbookPath.Exist() {
+			return sketchbookPath, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", sketch.ErrSketchNotFound,
+		tr("%[1]s was searched in the current directory and in %[2]s", sketchPath, strings.Join(sketchbookDirs.AsStrings(), ", ")))
+}
@@ -0,0 +1,86 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/arduino-cli/configuration"
+	rpc "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/commands/v1"
+	paths "github.com/arduino/go-paths-helper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSketchPath(t *testing.T) {
+	sketchbook := paths.New(t.TempDir())
+	require.NoError(t, sketchbook.Join("Blink").MkdirAll())
+	otherSketchbook := paths.New(t.TempDir())
+	require.NoError(t, otherSketchbook.Join("Blink").MkdirAll())
+	require.NoError(t, otherSketchbook.Join("Fade").MkdirAll())
+	sketchbooks := paths.NewPathList(sketchbook.String(), otherSketchbook.String())
+	outside := paths.New(t.TempDir()).Join("Project")
+	require.NoError(t, outside.MkdirAll())
+
+	resolve := func(sketchPath string, sketchbookDirs paths.PathList) string {
+		res, err := resolveSketchPath(sketchPath, sketchbookDirs)
+		require.NoError(t, err)
+		return res.String()
+	}
+
+	// Absolute paths are used as is
+	require.Equal(t, outside.String(), resolve(outside.String(), sketchbooks))
+	// Bare sketch names are searched in the sketchbooks, in order
+	require.Equal(t, sketchbook.Join("Blink").String(), resolve("Blink", sketchbooks))
+	require.Equal(t, otherSketchbook.Join("Fade").String(), resolve("Fade", sketchbooks))
+	require.Equal(t, otherSketchbook.Join("Blink").String(), resolve("Blink", paths.NewPathList(otherSketchbook.String(), sketchbook.String())))
+	// Relative paths are not searched in the sketchbooks
+	require.Equal(t, paths.New("Sketches", "Blink").String(), resolve(paths.New("Sketches", "Blink").String(), sketchbooks))
+	// Without sketchbooks the sketch path is left unchanged
+	require.Equal(t, "Blink", resolve("Blink", nil))
+	res, err := resolveSketchPath("", sketchbooks)
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	// Missing sketches report all the searched sketchbooks
+	_, err = resolveSketchPath("Missing", sketchbooks)
+	require.ErrorIs(t, err, sketch.ErrSketchNotFound)
+	require.Contains(t, err.Error(), sketchbook.String())
+	require.Contains(t, err.Error(), otherSketchbook.String())
+}
+
+func TestLoadSketchDefaults(t *testing.T) {
+	configuration.Settings = configuration.Init("")
+	sketchPath := paths.New(t.TempDir()).Join("Blink")
+	require.NoError(t, sketchPath.MkdirAll())
+	require.NoError(t, sketchPath.Join("Blink.ino").WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+
+	// Without sketch.yaml the defaults are empty
+	res, err := LoadSketch(context.Background(), &rpc.LoadSketchRequest{SketchPath: sketchPath.String()})
+	require.NoError(t, err)
+	require.Empty(t, res.GetDefaultFqbn())
+	require.Empty(t, res.GetDefaultPort())
+	require.Empty(t, res.GetDefaultProtocol())
+
+	projectFile := "default_fqbn: arduino:avr:uno\ndefault_port: /dev/ttyACM0\ndefault_protocol: serial\n"
+	require.NoError(t, sketchPath.Join("sketch.yaml").WriteFile([]byte(projectFile)))
+	res, err = LoadSketch(context.Background(), &rpc.LoadSketchRequest{SketchPath: sketchPath.String()})
+	require.NoError(t, err)
+	require.Equal(t, "arduino:avr:uno", res.GetDefaultFqbn())
+	require.Equal(t, "/dev/ttyACM0", res.GetDefaultPort())
+	require.Equal(t, "serial", res.GetDefaultProtocol())
+}
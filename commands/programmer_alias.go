@@ -0,0 +1,155 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package commands
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/arduino/arduino-cli/arduino"
+	"github.com/arduino/arduino-cli/arduino/cores"
+)
+
+// ResolveProgrammer works like FindProgrammer, but if no programmer has
+// exactly the given id, the id is matched with MatchProgrammerID against the
+// ids and the names of the programmers of the platforms. The id of the
+// programmer found is returned together with it. If no programmer matches,
+// a ProgrammerNotFoundError, suggesting the closest id if any, is returned.
+func ResolveProgrammer(programmer string, platforms ...*cores.PlatformRelease) (string, *cores.Programmer, error) {
+	if p := FindProgrammer(programmer, platforms...); p != nil {
+		return programmer, p, nil
+	}
+	names := map[string]string{}
+	for _, platform := range platforms {
+		if platform == nil {
+			continue
+		}
+		for id, p := range platform.Programmers {
+			if _, ok := names[id]; !ok {
+				names[id] = p.Name
+			}
+		}
+	}
+	id, suggestion := MatchProgrammerID(programmer, names)
+	if id == "" {
+		return "", nil, ProgrammerNotFoundError(programmer, suggestion)
+	}
+	return id, FindProgrammer(id, platforms...), nil
+}
+
+// ProgrammerNotFoundError returns the error reported when programmer is not
+// found, suggesting the given id, if not empty.
+func ProgrammerNotFoundError(programmer, suggestion string) error {
+	if suggestion == "" {
+		return &arduino.ProgrammerNotFoundError{Programmer: programmer}
+	}
+	return &arduino.ProgrammerNotFoundError{Programmer: programmer, Cause: errors.New(tr("did you mean '%s'?", suggestion))}
+}
+
+// MatchProgrammerID returns the id, among the keys of programmers (a map
+// from the programmer ids to their names), of the programmer requested with
+// programmer. An exact id always wins; otherwise the comparison ignores the
+// case and the punctuation (so "Atmel-ICE" and "atmel ice" are aliases of
+// "atmel_ice") and is done against the ids first and then the names, that
+// must match a single programmer. If no programmer matches an empty id is
+// returned, together with the id of a programmer with a similar name, if
+// any, to be suggested to the user.
+func MatchProgrammerID(programmer string, programmers map[string]string) (id string, suggestion string) {
+	if _, ok := programmers[programmer]; ok {
+		return programmer, ""
+	}
+
+	ids := make([]string, 0, len(programmers))
+	for id := range programmers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	normalized := normalizeProgrammerID(programmer)
+	if normalized == "" {
+		return "", ""
+	}
+	for _, useName := range []bool{false, true} {
+		matches := []string{}
+		for _, id := range ids {
+			alias := id
+			if useName {
+				alias = programmers[id]
+			}
+			if normalizeProgrammerID(alias) == normalized {
+				matches = append(matches, id)
+			}
+		}
+		if len(matches) == 1 {
+			return matches[0], ""
+		}
+		if len(matches) > 1 {
+			// ambiguous, let the user choose
+			return "", matches[0]
+		}
+	}
+
+	// suggest the closest id, if close enough
+	bestDistance := 3
+	for _, id := range ids {
+		if distance := editDistance(normalized, normalizeProgrammerID(id)); distance < bestDistance {
+			bestDistance = distance
+			suggestion = id
+		}
+	}
+	return "", suggestion
+}
+
+// normalizeProgrammerID returns the programmer id, or name, in lowercase and
+// without the characters that are not letters or digits.
+func normalizeProgrammerID(id string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, id)
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
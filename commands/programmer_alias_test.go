@@ -0,0 +1,89 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino"
+	"github.com/arduino/arduino-cli/arduino/cores"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchProgrammerID(t *testing.T) {
+	programmers := map[string]string{
+		"atmel_ice":  "Atmel-ICE",
+		"atmelice":   "Atmel ICE (legacy)",
+		"usbasp":     "USBasp",
+		"arduinoisp": "Arduino as ISP",
+	}
+	match := func(programmer string) (string, string) {
+		return MatchProgrammerID(programmer, programmers)
+	}
+
+	// exact ids are authoritative
+	id, _ := match("atmelice")
+	require.Equal(t, "atmelice", id)
+	id, _ = match("atmel_ice")
+	require.Equal(t, "atmel_ice", id)
+
+	// the aliases are matched ignoring case and punctuation
+	id, _ = match("USBasp")
+	require.Equal(t, "usbasp", id)
+	id, _ = match("Arduino as ISP")
+	require.Equal(t, "arduinoisp", id)
+	id, _ = match("arduino-as-isp")
+	require.Equal(t, "arduinoisp", id)
+
+	// ambiguous aliases are not resolved
+	id, suggestion := match("ATMEL-ICE")
+	require.Empty(t, id)
+	require.Equal(t, "atmel_ice", suggestion)
+
+	// near misses get a suggestion
+	id, suggestion = match("usbsap")
+	require.Empty(t, id)
+	require.Equal(t, "usbasp", suggestion)
+	id, suggestion = match("jlink")
+	require.Empty(t, id)
+	require.Empty(t, suggestion)
+}
+
+func TestResolveProgrammer(t *testing.T) {
+	usbasp := &cores.Programmer{Name: "USBasp"}
+	atmelICE := &cores.Programmer{Name: "Atmel-ICE"}
+	boardPlatform := &cores.PlatformRelease{Programmers: map[string]*cores.Programmer{"usbasp": usbasp}}
+	referencedPlatform := &cores.PlatformRelease{Programmers: map[string]*cores.Programmer{"atmel_ice": atmelICE}}
+
+	id, p, err := ResolveProgrammer("usbasp", boardPlatform, referencedPlatform)
+	require.NoError(t, err)
+	require.Equal(t, "usbasp", id)
+	require.Equal(t, usbasp, p)
+
+	id, p, err = ResolveProgrammer("Atmel-ICE", boardPlatform, nil, referencedPlatform)
+	require.NoError(t, err)
+	require.Equal(t, "atmel_ice", id)
+	require.Equal(t, atmelICE, p)
+
+	_, _, err = ResolveProgrammer("atmel_ise", boardPlatform, referencedPlatform)
+	var programmerErr *arduino.ProgrammerNotFoundError
+	require.ErrorAs(t, err, &programmerErr)
+	require.Equal(t, "Programmer 'atmel_ise' not found: did you mean 'atmel_ice'?", err.Error())
+
+	_, _, err = ResolveProgrammer("jlink", boardPlatform, referencedPlatform)
+	require.ErrorAs(t, err, &programmerErr)
+	require.Equal(t, "Programmer 'jlink' not found", err.Error())
+}
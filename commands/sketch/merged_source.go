@@ -0,0 +1,40 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package sketch
+
+import (
+	"github.com/arduino/arduino-cli/arduino"
+	"github.com/arduino/arduino-cli/arduino/builder"
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	paths "github.com/arduino/go-paths-helper"
+)
+
+// GetMergedSource returns the .cpp source produced by merging the .ino files
+// of the sketch in sketchPath, as done at the beginning of a compilation:
+// the Arduino.h inclusion is added if missing and every file is preceded by
+// its #line directive. The merge doesn't depend on the board, nothing is
+// compiled and nothing is written on disk.
+func GetMergedSource(sketchPath string) (string, error) {
+	sk, err := sketch.New(paths.New(sketchPath))
+	if err != nil {
+		return "", &arduino.CantOpenSketchError{Cause: err}
+	}
+	_, mergedSource, err := builder.NewBuilder(sk).MergedSketchSource(nil)
+	if err != nil {
+		return "", &arduino.CompileFailedError{Message: tr("Error merging sketch sources"), Cause: err}
+	}
+	return mergedSource, nil
+}
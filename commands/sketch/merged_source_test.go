@@ -0,0 +1,53 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package sketch
+
+import (
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino"
+	"github.com/arduino/arduino-cli/arduino/builder"
+	paths "github.com/arduino/go-paths-helper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMergedSource(t *testing.T) {
+	sketchPath := paths.New(t.TempDir()).Canonical().Join("Merged")
+	require.NoError(t, sketchPath.MkdirAll())
+	mainFile := sketchPath.Join("Merged.ino")
+	otherFile := sketchPath.Join("other.ino")
+	require.NoError(t, mainFile.WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	require.NoError(t, otherFile.WriteFile([]byte("void helper() {}\n")))
+
+	source, err := GetMergedSource(sketchPath.String())
+	require.NoError(t, err)
+	require.Equal(t,
+		"#include <Arduino.h>\n"+
+			"#line 1 "+builder.QuoteCppString(mainFile.String())+"\n"+
+			"void setup() {}\nvoid loop() {}\n\n"+
+			"#line 1 "+builder.QuoteCppString(otherFile.String())+"\n"+
+			"void helper() {}\n\n",
+		source)
+
+	// nothing is written in the sketch folder
+	files, err := sketchPath.ReadDirRecursive()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	_, err = GetMergedSource(sketchPath.Join("missing").String())
+	var openErr *arduino.CantOpenSketchError
+	require.ErrorAs(t, err, &openErr)
+}
@@ -0,0 +1,144 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/arduino/arduino-cli/arduino"
+	"github.com/arduino/arduino-cli/arduino/cores"
+	"github.com/arduino/arduino-cli/arduino/cores/packagemanager"
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/go-paths-helper"
+	"github.com/arduino/go-properties-orderedmap"
+)
+
+// SketchTargetRequest contains the fields, common to many requests, that
+// select a sketch and the board (and optionally the programmer) to use with it.
+type SketchTargetRequest struct {
+	SketchPath string
+	Fqbn       string
+	Programmer string
+}
+
+// SketchTarget is the sketch and the board (and optionally the programmer)
+// selected by a SketchTargetRequest, see ResolveSketchTarget.
+type SketchTarget struct {
+	Sketch *sketch.Sketch
+	FQBN   *cores.FQBN
+	// Package, Platform, Board, BoardProperties and BuildPlatform are the
+	// results of the resolution of the FQBN (see Explorer.ResolveFQBN)
+	Package         *cores.Package
+	Platform        *cores.PlatformRelease
+	Board           *cores.Board
+	BoardProperties *properties.Map
+	BuildPlatform   *cores.PlatformRelease
+	// Programmer is the ID of the programmer to use, empty if there is none
+	Programmer           string
+	ProgrammerProperties *properties.Map
+}
+
+// ResolveSketchTarget loads the sketch set in req and resolves the board to
+// use with it: the req.Fqbn or, if empty, the default FQBN of the sketch
+// (see SketchTargetFQBN).
+// The programmer to use is, in order of precedence, the req.Programmer, the
+// default programmer of the sketch or the default programmer of the board
+// (see BoardDefaultProgrammer). If a programmer is found its ID and its
+// properties are returned too, otherwise they are empty: a default
+// programmer that can't be found is ignored, only a req.Programmer that
+// can't be found is an error. The errors returned are the same of the
+// compile command.
+func ResolveSketchTarget(pme *packagemanager.Explorer, req *SketchTargetRequest) (*SketchTarget, error) {
+	if req.SketchPath == "" {
+		return nil, &arduino.MissingSketchPathError{}
+	}
+	sk, err := sketch.New(paths.New(req.SketchPath))
+	if err != nil {
+		return nil, &arduino.CantOpenSketchError{Cause: err}
+	}
+
+	fqbnIn := SketchTargetFQBN(sk, req.Fqbn)
+	if fqbnIn == "" {
+		return nil, &arduino.MissingFQBNError{}
+	}
+	fqbn, err := cores.ParseFQBN(fqbnIn)
+	if err != nil {
+		return nil, &arduino.InvalidFQBNError{Cause: err}
+	}
+
+	target := &SketchTarget{Sketch: sk, FQBN: fqbn}
+	target.Package, target.Platform, target.Board, target.BoardProperties, target.BuildPlatform, err = pme.ResolveFQBN(fqbn)
+	if err != nil {
+		if target.Platform == nil {
+			return nil, &arduino.PlatformNotFoundError{
+				Platform: fmt.Sprintf("%s:%s", fqbn.Package, fqbn.PlatformArch),
+				Cause:    fmt.Errorf(tr("platform not installed")),
+			}
+		}
+		return nil, &arduino.InvalidFQBNError{Cause: err}
+	}
+
+	programmerID := req.Programmer
+	if programmerID == "" {
+		programmerID = sk.GetDefaultProgrammer()
+	}
+	if programmerID == "" {
+		programmerID = BoardDefaultProgrammer(target.BoardProperties)
+	}
+	if programmerID != "" {
+		id, programmer, err := ResolveProgrammer(programmerID, target.Platform, target.BuildPlatform)
+		if err == nil {
+			target.Programmer = id
+			target.ProgrammerProperties = programmer.Properties
+		} else if req.Programmer != "" {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+// SketchTargetFQBN returns the FQBN to use with the sketch sk: fqbn or, if
+// empty, the default FQBN of the sketch. The sketch may be nil.
+func SketchTargetFQBN(sk *sketch.Sketch, fqbn string) string {
+	if fqbn == "" && sk != nil {
+		return sk.GetDefaultFQBN()
+	}
+	return fqbn
+}
+
+// BoardDefaultProgrammer returns the ID of the default programmer declared
+// by a board with the programmer.default property, or an empty string if
+// the board doesn't declare one.
+func BoardDefaultProgrammer(boardProperties *properties.Map) string {
+	return boardProperties.Get("programmer.default")
+}
+
+// FindProgrammer returns the programmer with the given id from the first of
+// the given platforms that defines it. The platforms are the board platform
+// followed by the platforms it references: a board may reference a single
+// platform for its core and variant, and a referenced platform can't
+// reference other platforms in turn. Nil platforms are skipped.
+func FindProgrammer(programmer string, platforms ...*cores.PlatformRelease) *cores.Programmer {
+	for _, platform := range platforms {
+		if platform == nil {
+			continue
+		}
+		if p, ok := platform.Programmers[programmer]; ok {
+			return p
+		}
+	}
+	return nil
+}
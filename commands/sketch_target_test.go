@@ -0,0 +1,121 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino"
+	"github.com/arduino/arduino-cli/arduino/cores/packagemanager"
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/go-paths-helper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSketchTarget(t *testing.T) {
+	hardware := paths.New(t.TempDir())
+	platformPath := hardware.Join("test", "avr")
+	require.NoError(t, platformPath.MkdirAll())
+	require.NoError(t, platformPath.Join("platform.txt").WriteFile([]byte("name=Test AVR\nversion=1.0.0\n")))
+	require.NoError(t, platformPath.Join("boards.txt").WriteFile([]byte("uno.name=Uno\nuno.build.core=arduino\n"+
+		"mega.name=Mega\nmega.build.core=arduino\nmega.programmer.default=isp\n"+
+		"nano.name=Nano\nnano.build.core=arduino\nnano.programmer.default=missing\n")))
+	require.NoError(t, platformPath.Join("programmers.txt").WriteFile([]byte("isp.name=ISP\nisp.program.tool=avrdude\n"+
+		"usbasp.name=USBasp\nusbasp.program.tool=avrdude\n")))
+	pmb := packagemanager.NewBuilder(nil, nil, nil, nil, "test")
+	pmb.LoadHardwareFromDirectory(hardware)
+	pme, release := pmb.Build().NewExplorer()
+	defer release()
+
+	sketchPath := paths.New(t.TempDir()).Join("Blink")
+	require.NoError(t, sketchPath.MkdirAll())
+	require.NoError(t, sketchPath.Join("Blink.ino").WriteFile([]byte{}))
+
+	req := &SketchTargetRequest{SketchPath: sketchPath.String(), Fqbn: "test:avr:uno", Programmer: "isp"}
+	target, err := ResolveSketchTarget(pme, req)
+	require.NoError(t, err)
+	require.Equal(t, "Blink", target.Sketch.Name)
+	require.Equal(t, "test:avr:uno", target.FQBN.String())
+	require.Equal(t, "test:avr", target.Platform.Platform.String())
+	require.Equal(t, target.Platform, target.BuildPlatform)
+	require.Equal(t, "Uno", target.Board.Name())
+	require.Equal(t, "arduino", target.BoardProperties.Get("build.core"))
+	require.Equal(t, "isp", target.Programmer)
+	require.Equal(t, "avrdude", target.ProgrammerProperties.Get("program.tool"))
+
+	// the FQBN defaults to the one of the sketch
+	req.Fqbn = ""
+	_, err = ResolveSketchTarget(pme, req)
+	require.ErrorAs(t, err, new(*arduino.MissingFQBNError))
+	require.NoError(t, sketchPath.Join("sketch.yaml").WriteFile([]byte("default_fqbn: test:avr:uno\n")))
+	target, err = ResolveSketchTarget(pme, req)
+	require.NoError(t, err)
+	require.Equal(t, "test:avr:uno", target.FQBN.String())
+
+	req.Programmer = ""
+	target, err = ResolveSketchTarget(pme, req)
+	require.NoError(t, err)
+	require.Empty(t, target.Programmer)
+	require.Nil(t, target.ProgrammerProperties)
+
+	// the default programmer of the board comes after the one of the sketch
+	req.Fqbn = "test:avr:mega"
+	target, err = ResolveSketchTarget(pme, req)
+	require.NoError(t, err)
+	require.Equal(t, "isp", target.Programmer)
+	require.NoError(t, sketchPath.Join("sketch.yaml").WriteFile([]byte("default_fqbn: test:avr:uno\ndefault_programmer: usbasp\n")))
+	target, err = ResolveSketchTarget(pme, req)
+	require.NoError(t, err)
+	require.Equal(t, "usbasp", target.Programmer)
+	req.Programmer = "isp"
+	target, err = ResolveSketchTarget(pme, req)
+	require.NoError(t, err)
+	require.Equal(t, "isp", target.Programmer)
+
+	req.Programmer = "missing"
+	_, err = ResolveSketchTarget(pme, req)
+	require.ErrorAs(t, err, new(*arduino.ProgrammerNotFoundError))
+
+	// a default programmer that can't be found means no programmer
+	req.Programmer = ""
+	require.NoError(t, sketchPath.Join("sketch.yaml").WriteFile([]byte("default_fqbn: test:avr:uno\ndefault_programmer: missing\n")))
+	target, err = ResolveSketchTarget(pme, req)
+	require.NoError(t, err)
+	require.Empty(t, target.Programmer)
+	require.Nil(t, target.ProgrammerProperties)
+	require.NoError(t, sketchPath.Join("sketch.yaml").WriteFile([]byte("default_fqbn: test:avr:uno\n")))
+	req.Fqbn = "test:avr:nano"
+	target, err = ResolveSketchTarget(pme, req)
+	require.NoError(t, err)
+	require.Empty(t, target.Programmer)
+	require.Nil(t, target.ProgrammerProperties)
+
+	req.Fqbn = "other:avr:uno"
+	_, err = ResolveSketchTarget(pme, req)
+	require.ErrorAs(t, err, new(*arduino.PlatformNotFoundError))
+
+	req.SketchPath = ""
+	_, err = ResolveSketchTarget(pme, req)
+	require.ErrorAs(t, err, new(*arduino.MissingSketchPathError))
+}
+
+func TestSketchTargetFQBN(t *testing.T) {
+	sk := &sketch.Sketch{Project: &sketch.Project{DefaultFqbn: "test:avr:uno"}}
+	require.Equal(t, "test:avr:mega", SketchTargetFQBN(sk, "test:avr:mega"))
+	require.Equal(t, "test:avr:uno", SketchTargetFQBN(sk, ""))
+	require.Equal(t, "test:avr:mega", SketchTargetFQBN(nil, "test:avr:mega"))
+	require.Equal(t, "", SketchTargetFQBN(nil, ""))
+}
@@ -50,9 +50,11 @@ func ListProgrammersAvailableForUpload(ctx context.Context, req *rpc.ListProgram
 	result := []*rpc.Programmer{}
 	createRPCProgrammer := func(id string, programmer *cores.Programmer) *rpc.Programmer {
 		return &rpc.Programmer{
-			Id:       id,
-			Platform: programmer.PlatformRelease.String(),
-			Name:     programmer.Name,
+			Id:            id,
+			Platform:      programmer.PlatformRelease.String(),
+			Name:          programmer.Name,
+			UploadCapable: programmer.SupportsUpload(),
+			DebugCapable:  programmer.SupportsDebug(),
 		}
 	}
 	if refPlatform != platform {
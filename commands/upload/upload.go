@@ -145,7 +145,7 @@ func Upload(ctx context.Context, req *rpc.UploadRequest, outStream io.Writer, er
 		sk,
 		req.GetImportFile(),
 		req.GetImportDir(),
-		req.GetFqbn(),
+		commands.SketchTargetFQBN(sk, req.GetFqbn()),
 		req.GetPort(),
 		req.GetProgrammer(),
 		req.GetVerbose(),
@@ -226,14 +226,12 @@ func runProgramAction(pme *packagemanager.Explorer,
 	// Extract programmer properties (when specified)
 	var programmer *cores.Programmer
 	if programmerID != "" {
-		programmer = boardPlatform.Programmers[programmerID]
-		if programmer == nil {
-			// Try to find the programmer in the referenced build platform
-			programmer = buildPlatform.Programmers[programmerID]
-		}
-		if programmer == nil {
-			return &arduino.ProgrammerNotFoundError{Programmer: programmerID}
+		// the programmer may also be defined in the referenced build platform
+		_, p, err := commands.ResolveProgrammer(programmerID, boardPlatform, buildPlatform)
+		if err != nil {
+			return err
 		}
+		programmer = p
 	}
 
 	// Determine upload tool
@@ -304,6 +302,12 @@ func runProgramAction(pme *packagemanager.Explorer,
 	}
 
 	if !uploadProperties.ContainsKey("upload.protocol") && programmer == nil {
+		// The board can be uploaded only using a programmer (for example
+		// because it has no bootloader): use its default programmer, if any
+		if defaultProgrammer := commands.BoardDefaultProgrammer(boardProperties); defaultProgrammer != "" {
+			return runProgramAction(pme, sk, importFile, importDir, fqbnIn, port, defaultProgrammer,
+				verbose, verify, burnBootloader, outStream, errStream, dryRun, userFields)
+		}
 		return &arduino.ProgrammerRequiredForUploadError{}
 	}
 
@@ -577,7 +581,7 @@ func determineBuildPathAndSketchName(importFile, importDir string, sk *sketch.Sk
 
 	// Case 4: only sketch specified. In this case we use the generated build path
 	// and the given sketch name.
-	return sk.DefaultBuildPath(), sk.Name + sk.MainFile.Ext(), nil
+	return sketch.BuildPathFor(sk, fqbn), sk.Name + sk.MainFile.Ext(), nil
 }
 
 func detectSketchNameFromBuildPath(buildPath *paths.Path) (string, error) {
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/arduino/arduino-cli/arduino"
 	"github.com/arduino/arduino-cli/arduino/cores"
 	"github.com/arduino/arduino-cli/arduino/cores/packagemanager"
 	"github.com/arduino/arduino-cli/arduino/sketch"
@@ -164,14 +165,20 @@ func TestUploadPropertiesComposition(t *testing.T) {
 		// 8: upload with programmer, require port through extra params
 		{buildPath1, "alice:avr:board1", "port", "serial", "progr3", false, "conf-board1 conf-general conf-program $$VERBOSE-VERIFY$$ prog3protocol port -bspeed testdata/build_path_1/sketch.ino.hex\n", ""},
 		{buildPath1, "alice:avr:board1", "", "", "progr3", false, "FAIL", ""},
-
-		// 10: burn bootloader, require port
+		// 10: upload of a board without bootloader, with its default programmer
+		{buildPath1, "alice:avr:board3", "", "", "", false, "conf-board3 conf-general conf-program $$VERBOSE-VERIFY$$ prog2protocol -bspeed testdata/build_path_1/sketch.ino.hex\n", ""},
+		{buildPath1, "alice:avr:board3", "port", "serial", "progr1", false, "conf-board3 conf-general conf-program $$VERBOSE-VERIFY$$ progprotocol port -bspeed testdata/build_path_1/sketch.ino.hex\n", ""},
+		// 12: upload of a board without bootloader, requires a programmer
+		{buildPath1, "alice:avr:board4", "", "", "", false, "FAIL", ""},
+		{buildPath1, "alice:avr:board4", "", "", "progr2", false, "conf-board4 conf-general conf-program $$VERBOSE-VERIFY$$ prog2protocol -bspeed testdata/build_path_1/sketch.ino.hex\n", ""},
+
+		// 14: burn bootloader, require port
 		{buildPath1, "alice:avr:board1", "port", "serial", "", true, "FAIL", ""}, // requires programmer
 		{buildPath1, "alice:avr:board1", "port", "serial", "progr1", true,
 			"ERASE conf-board1 conf-general conf-erase $$VERBOSE-VERIFY$$ genprog1protocol port -bspeed\n",
 			"BURN conf-board1 conf-general conf-bootloader $$VERBOSE-VERIFY$$ genprog1protocol port -bspeed -F0xFF " + cwd + "/testdata/hardware/alice/avr/bootloaders/niceboot/niceboot.hex\n"},
 
-		// 12: burn bootloader, preferences override from programmers.txt
+		// 16: burn bootloader, preferences override from programmers.txt
 		{buildPath1, "alice:avr:board1", "port", "serial", "progr4", true,
 			"ERASE conf-board1 conf-two-general conf-two-erase $$VERBOSE-VERIFY$$ prog4protocol-bootloader port -bspeed\n",
 			"BURN conf-board1 conf-two-general conf-two-bootloader $$VERBOSE-VERIFY$$ prog4protocol-bootloader port -bspeed -F0xFF " + cwd + "/testdata/hardware/alice/avr/bootloaders/niceboot/niceboot.hex\n"},
@@ -225,6 +232,34 @@ func TestUploadPropertiesComposition(t *testing.T) {
 	}
 }
 
+func TestUploadRequiresProgrammer(t *testing.T) {
+	pmb := packagemanager.NewBuilder(nil, nil, nil, nil, "test")
+	errs := pmb.LoadHardwareFromDirectory(paths.New("testdata", "hardware"))
+	require.Len(t, errs, 0)
+	pm := pmb.Build()
+	pme, release := pm.NewExplorer()
+	defer release()
+
+	err := runProgramAction(
+		pme,
+		nil, // sketch
+		"",  // importFile
+		paths.New("testdata", "build_path_1").String(), // importDir
+		"alice:avr:board4", // FQBN
+		nil,                // port
+		"",                 // programmer
+		false,              // verbose
+		false,              // verify
+		false,              // burnBootloader
+		&bytes.Buffer{},
+		&bytes.Buffer{},
+		true, // dryRun
+		map[string]string{},
+	)
+	var programmerErr *arduino.ProgrammerRequiredForUploadError
+	require.ErrorAs(t, err, &programmerErr)
+}
+
 func TestGetToolId(t *testing.T) {
 	props, err := properties.LoadFromBytes([]byte(`
 bootloader.tool=avrdude
@@ -39,6 +39,7 @@ func SetDefaults(settings *viper.Viper) {
 	settings.SetDefault("directories.Data", getDefaultArduinoDataDir())
 	settings.SetDefault("directories.Downloads", filepath.Join(getDefaultArduinoDataDir(), "staging"))
 	settings.SetDefault("directories.User", getDefaultUserDir())
+	settings.SetDefault("directories.additional_sketchbooks", []string{})
 
 	// Sketch compilation
 	settings.SetDefault("sketch.always_export_binaries", false)
@@ -53,6 +53,22 @@ func IDEBuiltinLibrariesDir(settings *viper.Viper) *paths.Path {
 	return paths.New(Settings.GetString("directories.builtin.Libraries"))
 }
 
+// SketchbookDirs returns the sketchbook directories where sketches are
+// searched by name: the user directory followed by the additional sketchbooks,
+// in the configured order.
+func SketchbookDirs(settings *viper.Viper) paths.PathList {
+	res := paths.PathList{}
+	if userDir := settings.GetString("directories.User"); userDir != "" {
+		res.Add(paths.New(userDir))
+	}
+	for _, dir := range settings.GetStringSlice("directories.additional_sketchbooks") {
+		if dir != "" {
+			res.Add(paths.New(dir))
+		}
+	}
+	return res
+}
+
 // LibrariesDir returns the full path to the user directory containing
 // custom libraries
 func LibrariesDir(settings *viper.Viper) *paths.Path {
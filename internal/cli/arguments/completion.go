@@ -17,12 +17,14 @@ package arguments
 
 import (
 	"context"
+	"sort"
 
 	"github.com/arduino/arduino-cli/arduino/cores"
 	"github.com/arduino/arduino-cli/commands"
 	"github.com/arduino/arduino-cli/commands/board"
 	"github.com/arduino/arduino-cli/commands/core"
 	"github.com/arduino/arduino-cli/commands/lib"
+	"github.com/arduino/arduino-cli/commands/upload"
 	"github.com/arduino/arduino-cli/internal/cli/instance"
 	rpc "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/commands/v1"
 )
@@ -84,6 +86,16 @@ func GetInstalledProtocols() []string {
 // GetInstalledProgrammers is an helper function useful to autocomplete.
 // It returns a list of programmers available based on the installed boards
 func GetInstalledProgrammers() []string {
+	return getInstalledProgrammers(false)
+}
+
+// GetInstalledDebugProgrammers is an helper function useful to autocomplete.
+// It returns the programmers of the installed boards that can be used to debug.
+func GetInstalledDebugProgrammers() []string {
+	return getInstalledProgrammers(true)
+}
+
+func getInstalledProgrammers(debugOnly bool) []string {
 	inst := instance.CreateAndInit()
 
 	// we need the list of the available fqbn in order to get the list of the programmers
@@ -106,6 +118,9 @@ func GetInstalledProgrammers() []string {
 		fqbn, _ := cores.ParseFQBN(board.Fqbn)
 		_, boardPlatform, _, _, _, _ := pme.ResolveFQBN(fqbn)
 		for programmerID, programmer := range boardPlatform.Programmers {
+			if debugOnly && !programmer.SupportsDebug() {
+				continue
+			}
 			installedProgrammers[programmerID] = programmer.Name
 		}
 	}
@@ -119,6 +134,42 @@ func GetInstalledProgrammers() []string {
 	return res
 }
 
+// GetProgrammersForBoard is an helper function useful to autocomplete.
+// It returns a list of programmers that can be used with the given board.
+// If the board can't be resolved the programmers of all the installed
+// boards are returned.
+func GetProgrammersForBoard(fqbn string) []string {
+	return getProgrammersForBoard(fqbn, false)
+}
+
+// GetDebugProgrammersForBoard works like GetProgrammersForBoard, but only
+// the programmers that can be used to debug are returned.
+func GetDebugProgrammersForBoard(fqbn string) []string {
+	return getProgrammersForBoard(fqbn, true)
+}
+
+func getProgrammersForBoard(fqbn string, debugOnly bool) []string {
+	inst := instance.CreateAndInit()
+
+	list, err := upload.ListProgrammersAvailableForUpload(context.Background(), &rpc.ListProgrammersAvailableForUploadRequest{
+		Instance: inst,
+		Fqbn:     fqbn,
+	})
+	if err != nil {
+		return getInstalledProgrammers(debugOnly)
+	}
+
+	res := []string{}
+	for _, programmer := range list.GetProgrammers() {
+		if debugOnly && !programmer.GetDebugCapable() {
+			continue
+		}
+		res = append(res, programmer.GetId()+"\t"+programmer.GetName())
+	}
+	sort.Strings(res)
+	return res
+}
+
 // GetUninstallableCores is an helper function useful to autocomplete.
 // It returns a list of cores which can be uninstalled
 func GetUninstallableCores() []string {
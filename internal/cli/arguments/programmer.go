@@ -15,24 +15,253 @@
 
 package arguments
 
-import "github.com/spf13/cobra"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/arduino/arduino-cli/arduino"
+	"github.com/arduino/arduino-cli/arduino/cores"
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/arduino-cli/commands"
+	"github.com/arduino/arduino-cli/commands/upload"
+	rpc "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/commands/v1"
+	"github.com/arduino/go-properties-orderedmap"
+	"github.com/spf13/cobra"
+)
 
 // Programmer contains the programmer flag data.
 // This is useful so all flags used by commands that need
 // this information are consistent with each other.
 type Programmer struct {
 	programmer string
+	fromPort   bool
+	// debugOnly restricts the completion to the programmers that can debug
+	debugOnly bool
 }
 
 // AddToCommand adds the flags used to set the programmer to the specified Command
 func (p *Programmer) AddToCommand(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&p.programmer, "programmer", "P", "", tr("Programmer to use, e.g: atmel_ice"))
+	cmd.Flags().StringVarP(&p.programmer, "programmer", "P", "", tr("Programmer to use, e.g: atmel_ice. A comma separated list may be given to use the first one available for the board, e.g: atmel_ice,stk500"))
 	cmd.RegisterFlagCompletionFunc("programmer", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// If the board has already been specified only its programmers are suggested
+		if fqbnFlag := cmd.Flag("fqbn"); fqbnFlag != nil && fqbnFlag.Value.String() != "" {
+			if p.debugOnly {
+				return GetDebugProgrammersForBoard(fqbnFlag.Value.String()), cobra.ShellCompDirectiveDefault
+			}
+			return GetProgrammersForBoard(fqbnFlag.Value.String()), cobra.ShellCompDirectiveDefault
+		}
+		if p.debugOnly {
+			return GetInstalledDebugProgrammers(), cobra.ShellCompDirectiveDefault
+		}
 		return GetInstalledProgrammers(), cobra.ShellCompDirectiveDefault
 	})
 }
 
+// AddToDebugCommand works like AddToCommand, but only the programmers that
+// can be used to debug are suggested by the completion of the flag
+func (p *Programmer) AddToDebugCommand(cmd *cobra.Command) {
+	p.debugOnly = true
+	p.AddToCommand(cmd)
+}
+
+// AddFromPortFlagToCommand adds the flag used to detect the programmer from
+// the port to the specified Command
+func (p *Programmer) AddFromPortFlagToCommand(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&p.fromPort, "programmer-from-port", false, tr("Detect the programmer to use from the identification properties of the port."))
+}
+
 // String returns the programmer
 func (p *Programmer) String() string {
 	return p.programmer
 }
+
+// GetProgrammerOrDefault returns the programmer specified by the user, or
+// the default programmer set in sketch.yaml (`default_programmer` key) if
+// the programmer flag is not set.
+func (p *Programmer) GetProgrammerOrDefault(sk *sketch.Sketch) string {
+	if p.programmer == "" && sk != nil {
+		return sk.GetDefaultProgrammer()
+	}
+	return p.programmer
+}
+
+// GetProgrammer returns the programmer to use, after checking that it can be
+// used with the board identified by fqbn. The programmer is determined by:
+// - the value of the programmer flag if explicitly specified, otherwise
+// - the programmer matching the given port, if requested with the
+// `--programmer-from-port` flag, otherwise
+// - the default programmer in sketch.yaml (`default_programmer` key), otherwise
+// - the default programmer of the board (`programmer.default` property)
+// If no programmer is set an empty string is returned. The programmer may be a
+// comma separated list: in that case the first programmer available for the
+// board is returned.
+func (p *Programmer) GetProgrammer(instance *rpc.Instance, fqbn string, port *rpc.Port, sk *sketch.Sketch) (string, error) {
+	return p.getProgrammer(instance, fqbn, port, sk, true)
+}
+
+func (p *Programmer) getProgrammer(instance *rpc.Instance, fqbn string, port *rpc.Port, sk *sketch.Sketch, useBoardDefault bool) (string, error) {
+	if p.programmer == "" && p.fromPort {
+		return detectProgrammer(instance, fqbn, port)
+	}
+	programmer := p.GetProgrammerOrDefault(sk)
+	if programmer == "" && useBoardDefault {
+		programmer = boardDefaultProgrammer(instance, fqbn)
+	}
+	if programmer == "" {
+		return "", nil
+	}
+	return checkProgrammerForBoard(instance, fqbn, programmer)
+}
+
+// checkProgrammerForBoard returns the programmer, among the comma separated
+// list programmers, to use with the board identified by fqbn (see
+// selectProgrammer).
+func checkProgrammerForBoard(instance *rpc.Instance, fqbn string, programmers string) (string, error) {
+	res, err := upload.ListProgrammersAvailableForUpload(context.Background(), &rpc.ListProgrammersAvailableForUploadRequest{
+		Instance: instance,
+		Fqbn:     fqbn,
+	})
+	if err != nil {
+		return "", err
+	}
+	return selectProgrammer(programmers, res.GetProgrammers())
+}
+
+// selectProgrammer returns the first programmer of the comma separated list
+// programmers that is one of the available programmers. If none of them is
+// available the returned error reports why each one has been discarded.
+func selectProgrammer(programmers string, available []*rpc.Programmer) (string, error) {
+	candidates := strings.Split(programmers, ",")
+	if len(candidates) == 1 {
+		return resolveAvailableProgrammer(programmers, available)
+	}
+	reasons := []string{}
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" {
+			continue
+		}
+		id, err := resolveAvailableProgrammer(candidate, available)
+		if err == nil {
+			return id, nil
+		}
+		reasons = append(reasons, err.Error())
+	}
+	return "", &arduino.ProgrammerNotFoundError{
+		Programmer: programmers,
+		Cause:      errors.New(tr("none of the programmers can be used: %s", strings.Join(reasons, "; "))),
+	}
+}
+
+// GetProgrammerForUpload works like GetProgrammer, but the default programmer
+// of the board is not used: the upload command uses it only if the board can
+// be uploaded only using a programmer (see upload.Upload).
+func (p *Programmer) GetProgrammerForUpload(instance *rpc.Instance, fqbn string, port *rpc.Port, sk *sketch.Sketch) (string, error) {
+	return p.getProgrammer(instance, fqbn, port, sk, false)
+}
+
+// boardDefaultProgrammer returns the default programmer declared by the board
+// identified by fqbn, or an empty string if there is none or the board can't
+// be resolved (the error is reported later by the command).
+func boardDefaultProgrammer(instance *rpc.Instance, fqbn string) string {
+	parsedFqbn, err := cores.ParseFQBN(fqbn)
+	if err != nil {
+		return ""
+	}
+
+	// FIXME: We must not access PackageManager directly here but use one of the commands.* functions
+	pme, release := commands.GetPackageManagerExplorer(&rpc.ListProgrammersAvailableForUploadRequest{Instance: instance})
+	if pme == nil {
+		return ""
+	}
+	defer release()
+	_, _, _, boardProperties, _, err := pme.ResolveFQBN(parsedFqbn)
+	if err != nil {
+		return ""
+	}
+	return commands.BoardDefaultProgrammer(boardProperties)
+}
+
+// resolveAvailableProgrammer returns the id of the programmer, among the
+// available ones, requested with programmer: it may be the exact id or one of
+// its aliases (see commands.MatchProgrammerID). If the programmer is not
+// available the returned error suggests a similar programmer or, if there is
+// none, lists the available programmers.
+func resolveAvailableProgrammer(programmer string, available []*rpc.Programmer) (string, error) {
+	names := map[string]string{}
+	ids := []string{}
+	for _, availableProgrammer := range available {
+		names[availableProgrammer.GetId()] = availableProgrammer.GetName()
+		ids = append(ids, availableProgrammer.GetId())
+	}
+	id, suggestion := commands.MatchProgrammerID(programmer, names)
+	if id != "" {
+		return id, nil
+	}
+	if len(ids) == 0 {
+		return "", &arduino.ProgrammerNotFoundError{Programmer: programmer, Cause: errors.New(tr("no programmers available for this board"))}
+	}
+	if suggestion != "" {
+		return "", commands.ProgrammerNotFoundError(programmer, suggestion)
+	}
+	sort.Strings(ids)
+	return "", &arduino.ProgrammerNotFoundError{Programmer: programmer, Cause: fmt.Errorf(tr("available programmers: %s"), strings.Join(ids, ", "))}
+}
+
+// detectProgrammer returns the programmer, among the ones available for the
+// board identified by fqbn, matching the identification properties of port.
+func detectProgrammer(instance *rpc.Instance, fqbn string, port *rpc.Port) (string, error) {
+	if port.GetAddress() == "" {
+		return "", &arduino.MissingPortError{}
+	}
+	parsedFqbn, err := cores.ParseFQBN(fqbn)
+	if err != nil {
+		return "", &arduino.InvalidFQBNError{Cause: err}
+	}
+
+	// FIXME: We must not access PackageManager directly here but use one of the commands.* functions
+	pme, release := commands.GetPackageManagerExplorer(&rpc.ListProgrammersAvailableForUploadRequest{Instance: instance})
+	if pme == nil {
+		return "", &arduino.InvalidInstanceError{}
+	}
+	defer release()
+	_, platform, _, _, refPlatform, err := pme.ResolveFQBN(parsedFqbn)
+	if err != nil {
+		return "", &arduino.UnknownFQBNError{Cause: err}
+	}
+	programmers := map[string]*cores.Programmer{}
+	if refPlatform != nil {
+		for id, programmer := range refPlatform.Programmers {
+			programmers[id] = programmer
+		}
+	}
+	for id, programmer := range platform.Programmers {
+		programmers[id] = programmer
+	}
+	return matchProgrammerToPort(programmers, port)
+}
+
+// matchProgrammerToPort returns the ID of the only programmer matching the
+// identification properties of port, or an error if none or more than one
+// programmer match.
+func matchProgrammerToPort(programmers map[string]*cores.Programmer, port *rpc.Port) (string, error) {
+	portProperties := properties.NewFromHashmap(port.GetProperties())
+	matches := []string{}
+	for id, programmer := range programmers {
+		if programmer.IsProgrammerMatchingIDProperties(portProperties) {
+			matches = append(matches, id)
+		}
+	}
+	sort.Strings(matches)
+	switch len(matches) {
+	case 0:
+		return "", &arduino.InvalidArgumentError{Message: tr("No programmer found for port %s", port.GetAddress())}
+	case 1:
+		return matches[0], nil
+	default:
+		return "", &arduino.InvalidArgumentError{Message: tr("Multiple programmers found for port %[1]s: %[2]s", port.GetAddress(), strings.Join(matches, ", "))}
+	}
+}
@@ -0,0 +1,133 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package arguments
+
+import (
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino"
+	"github.com/arduino/arduino-cli/arduino/cores"
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	rpc "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/commands/v1"
+	"github.com/arduino/go-properties-orderedmap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAvailableProgrammer(t *testing.T) {
+	available := []*rpc.Programmer{
+		{Id: "usbasp", Name: "USBasp"},
+		{Id: "atmel_ice", Name: "Atmel-ICE"},
+	}
+	prog, err := resolveAvailableProgrammer("atmel_ice", available)
+	require.NoError(t, err)
+	require.Equal(t, "atmel_ice", prog)
+
+	_, err = resolveAvailableProgrammer("jlink", available)
+	var programmerErr *arduino.ProgrammerNotFoundError
+	require.ErrorAs(t, err, &programmerErr)
+	require.Equal(t, "Programmer 'jlink' not found: available programmers: atmel_ice, usbasp", err.Error())
+
+	_, err = selectProgrammer("jlink", nil)
+	require.ErrorAs(t, err, &programmerErr)
+	require.Contains(t, err.Error(), "no programmers available")
+}
+
+func TestSelectProgrammer(t *testing.T) {
+	available := []*rpc.Programmer{
+		{Id: "usbasp", Name: "USBasp"},
+		{Id: "stk500", Name: "STK500"},
+	}
+	prog, err := selectProgrammer("usbasp", available)
+	require.NoError(t, err)
+	require.Equal(t, "usbasp", prog)
+
+	// the first available programmer of the chain is used
+	prog, err = selectProgrammer("atmel_ice,stk500,usbasp", available)
+	require.NoError(t, err)
+	require.Equal(t, "stk500", prog)
+	prog, err = selectProgrammer("atmel_ice, usbasp", available)
+	require.NoError(t, err)
+	require.Equal(t, "usbasp", prog)
+
+	// all the tried programmers are reported
+	_, err = selectProgrammer("atmel_ice,jlink", available)
+	var programmerErr *arduino.ProgrammerNotFoundError
+	require.ErrorAs(t, err, &programmerErr)
+	require.Equal(t, "atmel_ice,jlink", programmerErr.Programmer)
+	require.Contains(t, err.Error(), "Programmer 'atmel_ice' not found")
+	require.Contains(t, err.Error(), "Programmer 'jlink' not found")
+
+	// single programmers keep the same error
+	_, err = selectProgrammer("jlink", available)
+	require.Equal(t, "Programmer 'jlink' not found: available programmers: stk500, usbasp", err.Error())
+}
+
+func TestSelectProgrammerAliases(t *testing.T) {
+	available := []*rpc.Programmer{
+		{Id: "usbasp", Name: "USBasp"},
+		{Id: "atmel_ice", Name: "Atmel-ICE"},
+	}
+	// the aliases resolve to the programmer id
+	prog, err := selectProgrammer("Atmel-ICE", available)
+	require.NoError(t, err)
+	require.Equal(t, "atmel_ice", prog)
+	prog, err = selectProgrammer("jlink,USBASP", available)
+	require.NoError(t, err)
+	require.Equal(t, "usbasp", prog)
+
+	// near misses get a suggestion
+	_, err = selectProgrammer("atmel_ise", available)
+	require.Equal(t, "Programmer 'atmel_ise' not found: did you mean 'atmel_ice'?", err.Error())
+}
+
+func TestGetProgrammerOrDefault(t *testing.T) {
+	sk := &sketch.Sketch{Project: &sketch.Project{DefaultProgrammer: "atmel_ice"}}
+
+	// Empty flag, programmer from the sketch metadata
+	p := &Programmer{}
+	require.Equal(t, "atmel_ice", p.GetProgrammerOrDefault(sk))
+	require.Equal(t, "", p.GetProgrammerOrDefault(nil))
+
+	// The flag wins over the sketch metadata
+	p = &Programmer{programmer: "usbasp"}
+	require.Equal(t, "usbasp", p.GetProgrammerOrDefault(sk))
+	require.Equal(t, "usbasp", p.GetProgrammerOrDefault(nil))
+}
+
+func TestMatchProgrammerToPort(t *testing.T) {
+	newProgrammer := func(props map[string]string) *cores.Programmer {
+		return &cores.Programmer{Properties: properties.NewFromHashmap(props)}
+	}
+	programmers := map[string]*cores.Programmer{
+		"atmel_ice": newProgrammer(map[string]string{"upload_port.0.vid": "0x03eb", "upload_port.0.pid": "0x2141"}),
+		"edbg":      newProgrammer(map[string]string{"upload_port.0.vid": "0x03eb", "upload_port.0.pid": "0x2111"}),
+		"edbg_alt":  newProgrammer(map[string]string{"upload_port.0.vid": "0x03eb", "upload_port.0.pid": "0x2111"}),
+		"usbasp":    newProgrammer(map[string]string{"protocol": "usbasp"}),
+	}
+	port := func(pid string) *rpc.Port {
+		return &rpc.Port{Address: "/dev/ttyACM0", Properties: map[string]string{"vid": "0x03eb", "pid": pid}}
+	}
+
+	programmer, err := matchProgrammerToPort(programmers, port("0x2141"))
+	require.NoError(t, err)
+	require.Equal(t, "atmel_ice", programmer)
+
+	_, err = matchProgrammerToPort(programmers, port("0x2111"))
+	require.EqualError(t, err, "Multiple programmers found for port /dev/ttyACM0: edbg, edbg_alt")
+
+	_, err = matchProgrammerToPort(programmers, port("0x0000"))
+	require.EqualError(t, err, "No programmer found for port /dev/ttyACM0")
+}
@@ -19,12 +19,14 @@ import (
 	"context"
 	"os"
 
+	"github.com/arduino/arduino-cli/arduino/sketch"
 	"github.com/arduino/arduino-cli/commands/upload"
 	"github.com/arduino/arduino-cli/i18n"
 	"github.com/arduino/arduino-cli/internal/cli/arguments"
 	"github.com/arduino/arduino-cli/internal/cli/feedback"
 	"github.com/arduino/arduino-cli/internal/cli/instance"
 	rpc "github.com/arduino/arduino-cli/rpc/cc/arduino/cli/commands/v1"
+	"github.com/arduino/go-paths-helper"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -72,6 +74,17 @@ func runBootloaderCommand(command *cobra.Command, args []string) {
 		feedback.Fatal(tr("Error during Upload: %v", err), feedback.ErrGeneric)
 	}
 
+	// The sketch in the current directory, if any, may set the default programmer
+	var sk *sketch.Sketch
+	if wd, err := paths.Getwd(); err == nil {
+		sk, _ = sketch.New(wd)
+	}
+
+	prog, err := programmer.GetProgrammer(instance, fqbn.String(), discoveryPort.ToRPC(), sk)
+	if err != nil {
+		feedback.Fatal(tr("Error during Upload: %v", err), feedback.ErrGeneric)
+	}
+
 	stdOut, stdErr, res := feedback.OutputStreams()
 	if _, err := upload.BurnBootloader(context.Background(), &rpc.BurnBootloaderRequest{
 		Instance:   instance,
@@ -79,7 +92,7 @@ func runBootloaderCommand(command *cobra.Command, args []string) {
 		Port:       discoveryPort.ToRPC(),
 		Verbose:    verbose,
 		Verify:     verify,
-		Programmer: programmer.String(),
+		Programmer: prog,
 		DryRun:     dryRun,
 	}, stdOut, stdErr); err != nil {
 		feedback.Fatal(tr("Error during Upload: %v", err), feedback.ErrGeneric)
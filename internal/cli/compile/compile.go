@@ -48,7 +48,9 @@ var (
 	profileArg              arguments.Profile        // Profile to use
 	showPropertiesArg       arguments.ShowProperties // Show all build preferences used instead of compiling.
 	preprocess              bool                     // Print preprocessed code to stdout.
+	syntaxOnly              bool                     // Only check the syntax of the sketch sources.
 	buildCachePath          string                   // Builds of 'core.a' are saved into this path to be cached and reused.
+	sketchObjectCachePath   string                   // Object files of the sketch are saved into this path to be cached and reused.
 	buildPath               string                   // Path where to save compiled files.
 	buildProperties         []string                 // List of custom build properties separated by commas. Or can be used multiple times for multiple properties.
 	keysKeychain            string                   // The path of the dir where to search for the custom keys to sign and encrypt a binary. Used only by the platforms that supports it
@@ -96,7 +98,9 @@ func NewCommand() *cobra.Command {
 	compileCommand.Flags().BoolVar(&dumpProfile, "dump-profile", false, tr("Create and print a profile configuration from the build."))
 	showPropertiesArg.AddToCommand(compileCommand)
 	compileCommand.Flags().BoolVar(&preprocess, "preprocess", false, tr("Print preprocessed code to stdout instead of compiling."))
+	compileCommand.Flags().BoolVar(&syntaxOnly, "syntax-only", false, tr("Only check that the sketch compiles, without producing binaries."))
 	compileCommand.Flags().StringVar(&buildCachePath, "build-cache-path", "", tr("Builds of 'core.a' are saved into this path to be cached and reused."))
+	compileCommand.Flags().StringVar(&sketchObjectCachePath, "sketch-object-cache-path", "", tr("Object files of the sketch are saved into this path to be cached and reused, also by other build paths (object files with debug info are not cached). Looking up the cache requires to preprocess the sources, slowing down the builds that miss it."))
 	compileCommand.Flags().StringVarP(&exportDir, "output-dir", "", "", tr("Save build artifacts in this directory."))
 	compileCommand.Flags().StringVar(&buildPath, "build-path", "",
 		tr("Path where to save compiled files. If omitted, a directory will be created in the default temporary path of your OS."))
@@ -170,6 +174,8 @@ func runCompileCommand(cmd *cobra.Command, args []string) {
 	if keysKeychain != "" || signKey != "" || encryptKey != "" {
 		arguments.CheckFlagsMandatory(cmd, "keys-keychain", "sign-key", "encrypt-key")
 	}
+	// there are no binaries to upload after a syntax check
+	arguments.CheckFlagsConflicts(cmd, "syntax-only", "upload")
 
 	var overrides map[string]string
 	if sourceOverrides != "" {
@@ -213,7 +219,9 @@ func runCompileCommand(cmd *cobra.Command, args []string) {
 		SketchPath:                    sketchPath.String(),
 		ShowProperties:                showProperties != arguments.ShowPropertiesDisabled,
 		Preprocess:                    preprocess,
+		SyntaxCheckOnly:               syntaxOnly,
 		BuildCachePath:                buildCachePath,
+		SketchObjectCachePath:         sketchObjectCachePath,
 		BuildPath:                     buildPath,
 		BuildProperties:               buildProperties,
 		Warnings:                      warnings,
@@ -253,6 +261,11 @@ func runCompileCommand(cmd *cobra.Command, args []string) {
 			}
 		}
 
+		prog, err := programmer.GetProgrammerForUpload(inst, fqbn, port, sk)
+		if err != nil {
+			feedback.Fatal(tr("Error during Upload: %v", err), feedback.ErrGeneric)
+		}
+
 		uploadRequest := &rpc.UploadRequest{
 			Instance:   inst,
 			Fqbn:       fqbn,
@@ -261,11 +274,15 @@ func runCompileCommand(cmd *cobra.Command, args []string) {
 			Verbose:    verbose,
 			Verify:     verify,
 			ImportDir:  buildPath,
-			Programmer: programmer.String(),
+			Programmer: prog,
 			UserFields: fields,
 		}
 
 		if err := upload.Upload(context.Background(), uploadRequest, stdOut, stdErr); err != nil {
+			var programmerErr *arduino.ProgrammerRequiredForUploadError
+			if errors.As(err, &programmerErr) {
+				feedback.Fatal(tr("The board %s can be uploaded only using a programmer, please select one with the --programmer (-P) flag", fqbn), feedback.ErrBadArgument)
+			}
 			feedback.Fatal(tr("Error during Upload: %v", err), feedback.ErrGeneric)
 		}
 	}
@@ -330,7 +347,7 @@ func runCompileCommand(cmd *cobra.Command, args []string) {
 		ProfileOut:         profileOut,
 		Success:            compileError == nil,
 		showPropertiesMode: showProperties,
-		hideStats:          preprocess,
+		hideStats:          preprocess || syntaxOnly,
 	}
 
 	if compileError != nil {
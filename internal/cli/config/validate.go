@@ -23,25 +23,26 @@ import (
 )
 
 var validMap = map[string]reflect.Kind{
-	"board_manager.additional_urls": reflect.Slice,
-	"daemon.port":                   reflect.String,
-	"directories.data":              reflect.String,
-	"directories.downloads":         reflect.String,
-	"directories.user":              reflect.String,
-	"directories.builtin.tools":     reflect.String,
-	"directories.builtin.libraries": reflect.String,
-	"library.enable_unsafe_install": reflect.Bool,
-	"locale":                        reflect.String,
-	"logging.file":                  reflect.String,
-	"logging.format":                reflect.String,
-	"logging.level":                 reflect.String,
-	"sketch.always_export_binaries": reflect.Bool,
-	"metrics.addr":                  reflect.String,
-	"metrics.enabled":               reflect.Bool,
-	"network.proxy":                 reflect.String,
-	"network.user_agent_ext":        reflect.String,
-	"output.no_color":               reflect.Bool,
-	"updater.enable_notification":   reflect.Bool,
+	"board_manager.additional_urls":      reflect.Slice,
+	"daemon.port":                        reflect.String,
+	"directories.data":                   reflect.String,
+	"directories.downloads":              reflect.String,
+	"directories.user":                   reflect.String,
+	"directories.additional_sketchbooks": reflect.Slice,
+	"directories.builtin.tools":          reflect.String,
+	"directories.builtin.libraries":      reflect.String,
+	"library.enable_unsafe_install":      reflect.Bool,
+	"locale":                             reflect.String,
+	"logging.file":                       reflect.String,
+	"logging.format":                     reflect.String,
+	"logging.level":                      reflect.String,
+	"sketch.always_export_binaries":      reflect.Bool,
+	"metrics.addr":                       reflect.String,
+	"metrics.enabled":                    reflect.Bool,
+	"network.proxy":                      reflect.String,
+	"network.user_agent_ext":             reflect.String,
+	"output.no_color":                    reflect.Bool,
+	"updater.enable_notification":        reflect.Bool,
 }
 
 func typeOf(key string) (reflect.Kind, error) {
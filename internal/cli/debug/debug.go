@@ -39,8 +39,11 @@ var (
 	portArgs    arguments.Port
 	interpreter string
 	importDir   string
+	importFile  string
+	attach      bool
 	printInfo   bool
 	programmer  arguments.Programmer
+	debugServer string
 	tr          = i18n.Tr
 )
 
@@ -57,10 +60,14 @@ func NewCommand() *cobra.Command {
 
 	fqbnArg.AddToCommand(debugCommand)
 	portArgs.AddToCommand(debugCommand)
-	programmer.AddToCommand(debugCommand)
+	programmer.AddToDebugCommand(debugCommand)
+	programmer.AddFromPortFlagToCommand(debugCommand)
 	debugCommand.Flags().StringVar(&interpreter, "interpreter", "console", tr("Debug interpreter e.g.: %s", "console, mi, mi1, mi2, mi3"))
 	debugCommand.Flags().StringVarP(&importDir, "input-dir", "", "", tr("Directory containing binaries for debug."))
+	debugCommand.Flags().StringVarP(&importFile, "input-file", "", "", tr("Executable to debug, if different from the one found in the input directory."))
+	debugCommand.Flags().StringVar(&debugServer, "debug-server", "", tr("Debug server to use, if the board supports more than one (e.g.: %s).", "openocd, jlink"))
 	debugCommand.Flags().BoolVarP(&printInfo, "info", "I", false, tr("Show metadata about the debug session instead of starting the debugger."))
+	debugCommand.Flags().BoolVar(&attach, "attach", false, tr("Attach to the program running on the board, without uploading it nor resetting the board."))
 
 	return debugCommand
 }
@@ -77,6 +84,10 @@ func runDebugCommand(command *cobra.Command, args []string) {
 	sketchPath := arguments.InitSketchPath(path)
 	sk := arguments.NewSketch(sketchPath)
 	fqbn, port := arguments.CalculateFQBNAndPort(&portArgs, &fqbnArg, instance, sk)
+	prog, err := programmer.GetProgrammer(instance, fqbn, port, sk)
+	if err != nil {
+		feedback.FatalError(err, feedback.ErrBadArgument)
+	}
 	debugConfigRequested := &dbg.DebugConfigRequest{
 		Instance:    instance,
 		Fqbn:        fqbn,
@@ -84,7 +95,10 @@ func runDebugCommand(command *cobra.Command, args []string) {
 		Port:        port,
 		Interpreter: interpreter,
 		ImportDir:   importDir,
-		Programmer:  programmer.String(),
+		Executable:  importFile,
+		Attach:      attach,
+		Programmer:  prog,
+		DebugServer: debugServer,
 	}
 
 	if printInfo {
@@ -92,6 +106,9 @@ func runDebugCommand(command *cobra.Command, args []string) {
 		if res, err := debug.GetDebugConfig(context.Background(), debugConfigRequested); err != nil {
 			feedback.Fatal(tr("Error getting Debug info: %v", err), feedback.ErrBadArgument)
 		} else {
+			for _, warning := range res.GetWarnings() {
+				feedback.Warning(warning)
+			}
 			feedback.PrintResult(&debugInfoResult{res})
 		}
 
@@ -105,6 +122,13 @@ func runDebugCommand(command *cobra.Command, args []string) {
 		if err != nil {
 			feedback.FatalError(err, feedback.ErrBadArgument)
 		}
+		// The warnings are not mixed with the output of the debugger, that
+		// may be parsed by a frontend (e.g. with the mi interpreter)
+		if res, err := debug.GetDebugConfig(context.Background(), debugConfigRequested); err == nil {
+			for _, warning := range res.GetWarnings() {
+				feedback.Warning(warning)
+			}
+		}
 		if _, err := debug.Debug(context.Background(), debugConfigRequested, in, out, ctrlc); err != nil {
 			feedback.Fatal(tr("Error during Debug: %v", err), feedback.ErrGeneric)
 		}
@@ -139,6 +163,18 @@ func (r *debugInfoResult) String() string {
 	}
 	t.AddRow(tr("GDB Server type"), table.NewCell(r.info.GetServer(), green))
 	t.AddRow(tr("GDB Server path"), table.NewCell(r.info.GetServerPath(), dimGreen))
+	if cwd := r.info.GetServerWorkingDirectory(); cwd != "" {
+		t.AddRow(tr("GDB Server working directory"), table.NewCell(cwd, dimGreen))
+	}
+	if len(r.info.GetServerEnvironment()) > 0 {
+		env := properties.NewFromHashmap(r.info.GetServerEnvironment())
+		keys := env.Keys()
+		sort.Strings(keys)
+		t.AddRow(tr("GDB Server environment"))
+		for _, k := range keys {
+			t.AddRow(table.NewCell(" - "+k, dimGreen), table.NewCell(env.Get(k), dimGreen))
+		}
+	}
 	if len(r.info.GetServerConfiguration()) > 0 {
 		conf := properties.NewFromHashmap(r.info.GetServerConfiguration())
 		keys := conf.Keys()
@@ -148,5 +184,36 @@ func (r *debugInfoResult) String() string {
 			t.AddRow(table.NewCell(" - "+k, dimGreen), table.NewCell(conf.Get(k), dimGreen))
 		}
 	}
+	if host := r.info.GetPortHost(); host != "" {
+		t.AddRow(tr("Debug port host"), table.NewCell(host, dimGreen))
+		if tcpPort := r.info.GetPortTcpPort(); tcpPort != "" {
+			t.AddRow(tr("Debug port TCP port"), table.NewCell(tcpPort, dimGreen))
+		}
+	}
+	if additional := r.info.GetAdditionalExecutables(); len(additional) > 0 {
+		t.AddRow(tr("Additional executables"))
+		for _, executable := range additional {
+			t.AddRow(table.NewCell(" - "+executable, dimGreen))
+		}
+	}
+	if svdFile := r.info.GetSvdFile(); svdFile != "" {
+		t.AddRow(tr("SVD file"), table.NewCell(svdFile, dimGreen))
+	}
+	if len(r.info.GetCortexDebugConfiguration()) > 0 {
+		conf := properties.NewFromHashmap(r.info.GetCortexDebugConfiguration())
+		keys := conf.Keys()
+		sort.Strings(keys)
+		t.AddRow(tr("Configuration options for %s", "cortex-debug"))
+		for _, k := range keys {
+			t.AddRow(table.NewCell(" - "+k, dimGreen), table.NewCell(conf.Get(k), dimGreen))
+		}
+	}
+	if missing := r.info.GetMissingTools(); len(missing) > 0 {
+		red := color.New(color.FgHiRed)
+		t.AddRow(tr("Missing tools"))
+		for _, tool := range missing {
+			t.AddRow(table.NewCell(" - "+tool, red))
+		}
+	}
 	return t.Render()
 }
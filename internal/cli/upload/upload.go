@@ -72,6 +72,7 @@ func NewCommand() *cobra.Command {
 	uploadCommand.Flags().BoolVarP(&verify, "verify", "t", false, tr("Verify uploaded binary after the upload."))
 	uploadCommand.Flags().BoolVarP(&verbose, "verbose", "v", false, tr("Optional, turns on verbose mode."))
 	programmer.AddToCommand(uploadCommand)
+	programmer.AddFromPortFlagToCommand(uploadCommand)
 	uploadCommand.Flags().BoolVar(&dryRun, "dry-run", false, tr("Do not perform the actual upload, just log out actions"))
 	uploadCommand.Flags().MarkHidden("dry-run")
 	return uploadCommand
@@ -137,6 +138,11 @@ func runUploadCommand(command *cobra.Command, args []string) {
 		feedback.Fatal(msg, feedback.ErrGeneric)
 	}
 
+	prog, err := programmer.GetProgrammerForUpload(instance, fqbn, port, sk)
+	if err != nil {
+		feedback.FatalError(err, feedback.ErrBadArgument)
+	}
+
 	fields := map[string]string{}
 	if len(userFieldRes.UserFields) > 0 {
 		feedback.Print(tr("Uploading to specified board using %s protocol requires the following info:", port.Protocol))
@@ -162,11 +168,15 @@ func runUploadCommand(command *cobra.Command, args []string) {
 		Verify:     verify,
 		ImportFile: importFile,
 		ImportDir:  importDir,
-		Programmer: programmer.String(),
+		Programmer: prog,
 		DryRun:     dryRun,
 		UserFields: fields,
 	}
 	if err := upload.Upload(context.Background(), req, stdOut, stdErr); err != nil {
+		var programmerErr *arduino.ProgrammerRequiredForUploadError
+		if errors.As(err, &programmerErr) {
+			feedback.Fatal(tr("The board %s can be uploaded only using a programmer, please select one with the --programmer (-P) flag", fqbn), feedback.ErrBadArgument)
+		}
 		feedback.FatalError(err, feedback.ErrGeneric)
 	}
 	feedback.PrintResult(stdIOResult())
@@ -180,3 +180,23 @@ func TestCompileWithInvalidLibrary(t *testing.T) {
 	require.NoError(t, err)
 	require.Contains(t, string(stderr), fmt.Sprintf("loading library from %s: invalid library: no header files found", emptyLibPath))
 }
+
+func TestCompileAndUploadWithSketchDefaultProgrammer(t *testing.T) {
+	env, cli := integrationtest.CreateArduinoCLIWithEnvironment(t)
+	defer env.CleanUp()
+
+	_, _, err := cli.Run("core", "install", "arduino:avr@1.8.5")
+	require.NoError(t, err)
+
+	sketchPath := cli.SketchbookDir().Join("SketchWithDefaultProgrammer")
+	_, _, err = cli.Run("sketch", "new", sketchPath.String())
+	require.NoError(t, err)
+	err = sketchPath.Join("sketch.yaml").WriteFile([]byte("default_programmer: not-a-programmer\n"))
+	require.NoError(t, err)
+
+	// The upload uses the default programmer of the sketch, that is not
+	// available for the board
+	_, stderr, err := cli.Run("compile", "-b", "arduino:avr:uno", "-u", "-p", "/dev/ttyNOTEXISTING", sketchPath.String())
+	require.Error(t, err)
+	require.Contains(t, string(stderr), "not-a-programmer")
+}
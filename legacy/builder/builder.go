@@ -19,7 +19,6 @@ import (
 	"reflect"
 	"time"
 
-	"github.com/arduino/arduino-cli/arduino/builder"
 	"github.com/arduino/arduino-cli/i18n"
 	"github.com/arduino/arduino-cli/legacy/builder/phases"
 	"github.com/arduino/arduino-cli/legacy/builder/types"
@@ -40,7 +39,6 @@ func (s *Builder) Run(ctx *types.Context) error {
 		return err
 	}
 
-	var _err error
 	commands := []types.Command{
 		&ContainerSetupHardwareToolsLibsSketchAndProps{},
 
@@ -48,10 +46,9 @@ func (s *Builder) Run(ctx *types.Context) error {
 
 		&RecipeByPrefixSuffixRunner{Prefix: "recipe.hooks.prebuild", Suffix: ".pattern"},
 
-		types.BareCommand(func(ctx *types.Context) error {
-			ctx.LineOffset, ctx.SketchSourceMerged, _err = builder.PrepareSketchBuildPath(ctx.Sketch, ctx.SourceOverride, ctx.SketchBuildPath)
-			return _err
-		}),
+		&PrepareSketchBuildPath{},
+
+		&WarnAboutMissingIncludeGuards{},
 
 		utils.LogIfVerbose(false, tr("Detecting libraries used...")),
 		&ContainerFindIncludes{},
@@ -132,7 +129,6 @@ func (s *Preprocess) Run(ctx *types.Context) error {
 		return err
 	}
 
-	var _err error
 	commands := []types.Command{
 		&ContainerSetupHardwareToolsLibsSketchAndProps{},
 
@@ -140,10 +136,7 @@ func (s *Preprocess) Run(ctx *types.Context) error {
 
 		&RecipeByPrefixSuffixRunner{Prefix: "recipe.hooks.prebuild", Suffix: ".pattern"},
 
-		types.BareCommand(func(ctx *types.Context) error {
-			ctx.LineOffset, ctx.SketchSourceMerged, _err = builder.PrepareSketchBuildPath(ctx.Sketch, ctx.SourceOverride, ctx.SketchBuildPath)
-			return _err
-		}),
+		&PrepareSketchBuildPath{},
 
 		&ContainerFindIncludes{},
 
@@ -161,6 +154,36 @@ func (s *Preprocess) Run(ctx *types.Context) error {
 	return nil
 }
 
+// SyntaxCheck prepares and preprocesses the sketch like Builder, then checks
+// that the sketch sources compile without producing object files and
+// without building the libraries and the core, nor linking.
+type SyntaxCheck struct{}
+
+func (s *SyntaxCheck) Run(ctx *types.Context) error {
+	if err := ctx.BuildPath.MkdirAll(); err != nil {
+		return err
+	}
+
+	commands := []types.Command{
+		&ContainerSetupHardwareToolsLibsSketchAndProps{},
+
+		&ContainerBuildOptions{},
+
+		&PrepareSketchBuildPath{},
+
+		utils.LogIfVerbose(false, tr("Detecting libraries used...")),
+		&ContainerFindIncludes{},
+
+		utils.LogIfVerbose(false, tr("Generating function prototypes...")),
+		&PreprocessSketch{},
+
+		utils.LogIfVerbose(false, tr("Checking sketch syntax...")),
+		&phases.SketchSyntaxChecker{},
+	}
+
+	return runCommands(ctx, commands)
+}
+
 func runCommands(ctx *types.Context, commands []types.Command) error {
 	ctx.Progress.AddSubSteps(len(commands))
 	defer ctx.Progress.RemoveSubSteps()
@@ -193,6 +216,11 @@ func RunParseHardware(ctx *types.Context) error {
 	return runCommands(ctx, commands)
 }
 
+func RunSyntaxCheck(ctx *types.Context) error {
+	command := SyntaxCheck{}
+	return command.Run(ctx)
+}
+
 func RunPreprocess(ctx *types.Context) error {
 	command := Preprocess{}
 	return command.Run(ctx)
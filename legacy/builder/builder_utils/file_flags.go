@@ -0,0 +1,66 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder_utils
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arduino/go-paths-helper"
+)
+
+// FileFlags are the extra compiler flags to use for some of the source files.
+// The flags are selected with glob patterns (see path.Match) matched against
+// the path of the source file relative to a root folder, using forward
+// slashes as separator.
+type FileFlags struct {
+	root     *paths.Path
+	patterns map[string]string
+}
+
+// NewFileFlags creates a FileFlags matching the patterns against the paths
+// relative to root.
+func NewFileFlags(root *paths.Path, patterns map[string]string) *FileFlags {
+	return &FileFlags{root: root, patterns: patterns}
+}
+
+// Get returns the extra flags for source. If more patterns match, the flags
+// are concatenated in the lexicographic order of the patterns.
+func (f *FileFlags) Get(source *paths.Path) string {
+	if f == nil || len(f.patterns) == 0 {
+		return ""
+	}
+	rel, err := f.root.RelTo(source)
+	if err != nil {
+		return ""
+	}
+	relPath := filepath.ToSlash(rel.String())
+
+	patterns := []string{}
+	for pattern := range f.patterns {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	flags := []string{}
+	for _, pattern := range patterns {
+		if match, err := path.Match(pattern, relPath); err == nil && match {
+			flags = append(flags, f.patterns[pattern])
+		}
+	}
+	return strings.Join(flags, " ")
+}
@@ -0,0 +1,201 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder_utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/arduino/arduino-cli/legacy/builder/types"
+	"github.com/arduino/arduino-cli/legacy/builder/utils"
+	"github.com/arduino/go-paths-helper"
+	"github.com/arduino/go-properties-orderedmap"
+	"github.com/pkg/errors"
+)
+
+// buildPathPlaceholder replaces the build path in the command lines used to
+// compute the cache keys and in the cached dependency files, so that the cache
+// can be shared among different build paths.
+const buildPathPlaceholder = "{build.path}"
+
+// cachedSource describes how the sources with a given extension are
+// preprocessed to compute their cache key: the placeholder of their compiler
+// flags in the compile recipe and the language given to the preprocessor.
+type cachedSource struct {
+	flags    string
+	language string
+}
+
+// cachedSources are the sources whose object files can be cached, keyed by
+// extension
+var cachedSources = map[string]cachedSource{
+	".cpp": {flags: "{compiler.cpp.flags}", language: "c++"},
+	".c":   {flags: "{compiler.c.flags}", language: "c"},
+	".S":   {flags: "{compiler.S.flags}", language: "assembler-with-cpp"},
+}
+
+// ObjectCache is a content-addressed cache of object files. An object file is
+// identified by the hash of its preprocessed source, of the command line
+// used to compile it and of the compiler executable, so it can be reused by
+// builds in different build paths. The C, C++ and assembly sources are
+// cached.
+//
+// Computing the key requires to run the preprocessor on each source that is
+// not up to date, so a build that doesn't find its object files in the cache
+// takes longer than a build without the cache. The object files compiled with
+// debug info are not cached, since they contain the paths of the build path
+// where they have been compiled. The macros expanding to a path of the build
+// path (like __FILE__) are part of the key, so their object files are reused
+// only in the same build path.
+type ObjectCache struct {
+	dir       *paths.Path
+	buildPath *paths.Path
+}
+
+// NewObjectCache creates an ObjectCache stored in dir, for the object files
+// compiled in buildPath.
+func NewObjectCache(dir *paths.Path, buildPath *paths.Path) *ObjectCache {
+	return &ObjectCache{dir: dir, buildPath: buildPath}
+}
+
+// key returns the cache key of objectFile, compiled from source with the
+// given command. The source is preprocessed with the same flags.
+func (c *ObjectCache) key(ctx *types.Context, source, objectFile *paths.Path, buildProperties *properties.Map, command *exec.Cmd) (string, error) {
+	sourceType, ok := cachedSources[source.Ext()]
+	if !ok {
+		return "", errors.New(tr("only C, C++ and assembly sources are cached"))
+	}
+	if hasDebugInfo(command.Args) {
+		return "", errors.New(tr("the object files with debug info are not cached"))
+	}
+	preprocessedFile := paths.New(objectFile.String() + ".preproc")
+	defer preprocessedFile.Remove()
+
+	// the line markers are left out, since they contain the build path
+	preprocProperties := buildProperties.Clone()
+	preprocProperties.Set("preproc.macros.flags", "-w -x "+sourceType.language+" -E -CC -P")
+	preprocProperties.SetPath("preprocessed_file_path", preprocessedFile)
+	if source.Ext() != ".cpp" || preprocProperties.Get("recipe.preproc.macros") == "" {
+		// autogenerate preprocess macros recipe from compile recipe
+		preprocPattern := preprocProperties.Get("recipe" + source.Ext() + ".o.pattern")
+		if !strings.Contains(preprocPattern, sourceType.flags) {
+			return "", errors.New(tr("the compile recipe can't be used to preprocess the source"))
+		}
+		preprocPattern = strings.Replace(preprocPattern, sourceType.flags, sourceType.flags+" {preproc.macros.flags}", 1)
+		preprocPattern = strings.Replace(preprocPattern, "{object_file}", "{preprocessed_file_path}", 1)
+		preprocProperties.Set("recipe.preproc.macros", preprocPattern)
+	}
+	preprocCommand, err := PrepareCommandForRecipe(preprocProperties, "recipe.preproc.macros", true, ctx.PackageManager.GetEnvVarsForSpawnedProcess())
+	if err != nil {
+		return "", err
+	}
+	// The dependency file is not needed
+	preprocCommand.Args = utils.Filter(preprocCommand.Args, func(a string) bool { return a != "-MMD" })
+	if _, _, err := utils.ExecCommand(ctx, preprocCommand, utils.Capture, utils.Capture); err != nil {
+		return "", err
+	}
+	preprocessed, err := preprocessedFile.ReadFile()
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, arg := range command.Args {
+		hash.Write([]byte(c.normalize(arg)))
+		hash.Write([]byte{0})
+	}
+	// Changes of the compiler executable invalidate the cache
+	if compiler, err := exec.LookPath(command.Args[0]); err == nil {
+		if info, err := paths.New(compiler).Stat(); err == nil {
+			fmt.Fprintf(hash, "%d %d", info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	hash.Write([]byte{0})
+	// the preprocessed source is not normalized: the paths of the build path
+	// expanded by the macros end up in the object file
+	hash.Write(preprocessed)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hasDebugInfo returns true if the compiler arguments args request the
+// generation of debug info (-g, -g3, -ggdb...), unless it's disabled by a
+// following -g0. The options only changing the format of the debug info (like
+// -gz or -gno-record-gcc-switches) are ignored.
+func hasDebugInfo(args []string) bool {
+	res := false
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-g") || strings.HasPrefix(arg, "-gno-") || strings.HasPrefix(arg, "-gz") {
+			continue
+		}
+		res = arg != "-g0"
+	}
+	return res
+}
+
+// restore copies the object file and the dependency file identified by key
+// in place of objectFile and depsFile. It returns false if key is not cached.
+func (c *ObjectCache) restore(key string, objectFile, depsFile *paths.Path) bool {
+	cachedObject := c.dir.Join(key, "object.o")
+	cachedDeps, err := c.dir.Join(key, "object.d").ReadFile()
+	if err != nil || cachedObject.NotExist() {
+		return false
+	}
+	if err := cachedObject.CopyTo(objectFile); err != nil {
+		return false
+	}
+	deps := strings.ReplaceAll(string(cachedDeps), buildPathPlaceholder, c.buildPath.String())
+	if err := depsFile.WriteFile([]byte(deps)); err != nil {
+		objectFile.Remove()
+		return false
+	}
+	return true
+}
+
+// store saves objectFile and depsFile in the cache with the given key.
+func (c *ObjectCache) store(key string, objectFile, depsFile *paths.Path) error {
+	deps, err := depsFile.ReadFile()
+	if err != nil {
+		return err
+	}
+	// Save in a temporary folder and then rename it, so that concurrent
+	// builds never see a partially written entry
+	if err := c.dir.MkdirAll(); err != nil {
+		return err
+	}
+	tmp, err := paths.MkTempDir(c.dir.String(), "tmp-"+key)
+	if err != nil {
+		return err
+	}
+	defer tmp.RemoveAll()
+	if err := objectFile.CopyTo(tmp.Join("object.o")); err != nil {
+		return err
+	}
+	if err := tmp.Join("object.d").WriteFile([]byte(c.normalize(string(deps)))); err != nil {
+		return err
+	}
+	if c.dir.Join(key).Exist() {
+		return nil
+	}
+	return tmp.Rename(c.dir.Join(key))
+}
+
+// normalize replaces the build path in s with a placeholder
+func (c *ObjectCache) normalize(s string) string {
+	return strings.ReplaceAll(s, c.buildPath.String(), buildPathPlaceholder)
+}
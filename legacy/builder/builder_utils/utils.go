@@ -23,6 +23,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/arduino/arduino-cli/arduino/globals"
 	"github.com/arduino/arduino-cli/i18n"
@@ -69,14 +70,69 @@ func findAllFilesInFolder(sourcePath string, recurse bool) ([]string, error) {
 }
 
 func CompileFiles(ctx *types.Context, sourcePath *paths.Path, buildPath *paths.Path, buildProperties *properties.Map, includes []string) (paths.PathList, error) {
-	return compileFiles(ctx, sourcePath, false, buildPath, buildProperties, includes)
+	return compileFiles(ctx, sourcePath, false, buildPath, buildProperties, includes, nil, nil)
 }
 
 func CompileFilesRecursive(ctx *types.Context, sourcePath *paths.Path, buildPath *paths.Path, buildProperties *properties.Map, includes []string) (paths.PathList, error) {
-	return compileFiles(ctx, sourcePath, true, buildPath, buildProperties, includes)
+	return compileFiles(ctx, sourcePath, true, buildPath, buildProperties, includes, nil, nil)
 }
 
-func compileFiles(ctx *types.Context, sourcePath *paths.Path, recurse bool, buildPath *paths.Path, buildProperties *properties.Map, includes []string) (paths.PathList, error) {
+// CompileFilesWithCache works like CompileFiles (or CompileFilesRecursive if
+// recurse is true), but the object files are taken from the given cache when
+// possible, and the compiled ones are added to the cache. The fileFlags, if
+// not nil, are appended to the compiler extra flags of the matching files.
+func CompileFilesWithCache(ctx *types.Context, sourcePath *paths.Path, recurse bool, buildPath *paths.Path, buildProperties *properties.Map, includes []string, cache *ObjectCache, fileFlags *FileFlags) (paths.PathList, error) {
+	return compileFiles(ctx, sourcePath, recurse, buildPath, buildProperties, includes, cache, fileFlags)
+}
+
+// CheckFilesSyntax runs the compiler in syntax check mode (-fsyntax-only) on
+// the sources in sourcePath (recursively if recurse is true), using the same
+// recipes, properties and includes of CompileFiles. The compiler diagnostics
+// are written to the ctx error stream. Nothing is written in the build path:
+// the compiler is run in a temporary folder removed before returning, and
+// the commands are not added to the compilation database.
+func CheckFilesSyntax(ctx *types.Context, sourcePath *paths.Path, recurse bool, buildProperties *properties.Map, includes []string) error {
+	tmp, err := paths.MkTempDir("", "arduino-syntax-check")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tmp.RemoveAll()
+
+	compilationDatabase := ctx.CompilationDatabase
+	ctx.CompilationDatabase = nil
+	defer func() { ctx.CompilationDatabase = compilationDatabase }()
+
+	// assembly sources are not checked, they're just assembled in the
+	// temporary folder
+	properties := buildProperties.Clone()
+	for _, key := range []string{"compiler.c.extra_flags", "compiler.cpp.extra_flags"} {
+		properties.Set(key, strings.TrimSpace(properties.Get(key)+" -fsyntax-only"))
+	}
+	_, err = compileFiles(ctx, sourcePath, recurse, tmp, properties, includes, nil, nil)
+	return err
+}
+
+// PredictObjectFiles returns the object files that CompileFiles (or
+// CompileFilesRecursive if recurse is true) would produce for the sources
+// in sourcePath, without compiling anything.
+func PredictObjectFiles(sourcePath *paths.Path, recurse bool, buildPath *paths.Path) (paths.PathList, error) {
+	sources, err := findSourceFiles(sourcePath, recurse)
+	if err != nil {
+		return nil, err
+	}
+	objectFiles := paths.NewPathList()
+	for _, source := range sources {
+		objectFile, err := objectFilePath(sourcePath, source, buildPath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		objectFiles.Add(objectFile)
+	}
+	objectFiles.Sort()
+	return objectFiles, nil
+}
+
+func findSourceFiles(sourcePath *paths.Path, recurse bool) (paths.PathList, error) {
 	var sources paths.PathList
 	var err error
 	if recurse {
@@ -94,6 +150,22 @@ func compileFiles(ctx *types.Context, sourcePath *paths.Path, recurse bool, buil
 	}
 
 	sources.FilterSuffix(validExtensions...)
+	return sources, nil
+}
+
+func objectFilePath(sourcePath *paths.Path, source *paths.Path, buildPath *paths.Path) (*paths.Path, error) {
+	relativeSource, err := sourcePath.RelTo(source)
+	if err != nil {
+		return nil, err
+	}
+	return buildPath.Join(relativeSource.String() + ".o"), nil
+}
+
+func compileFiles(ctx *types.Context, sourcePath *paths.Path, recurse bool, buildPath *paths.Path, buildProperties *properties.Map, includes []string, cache *ObjectCache, fileFlags *FileFlags) (paths.PathList, error) {
+	sources, err := findSourceFiles(sourcePath, recurse)
+	if err != nil {
+		return nil, err
+	}
 	ctx.Progress.AddSubSteps(len(sources))
 	defer ctx.Progress.RemoveSubSteps()
 
@@ -108,7 +180,7 @@ func compileFiles(ctx *types.Context, sourcePath *paths.Path, recurse bool, buil
 	queue := make(chan *paths.Path)
 	job := func(source *paths.Path) {
 		recipe := fmt.Sprintf("recipe%s.o.pattern", source.Ext())
-		objectFile, err := compileFileWithRecipe(ctx, sourcePath, source, buildPath, buildProperties, includes, recipe)
+		objectFile, err := compileFileWithRecipe(ctx, sourcePath, source, buildPath, buildProperties, includes, recipe, cache, fileFlags)
 		if err != nil {
 			errorsMux.Lock()
 			errorsList = append(errorsList, err)
@@ -136,12 +208,13 @@ func compileFiles(ctx *types.Context, sourcePath *paths.Path, recurse bool, buil
 		}()
 	}
 
-	// Feed jobs until error or done
+	// Feed jobs until error or done, or until all the sources have been
+	// processed if ctx.CompileKeepGoing is set
 	for _, source := range sources {
 		errorsMux.Lock()
 		gotError := len(errorsList) > 0
 		errorsMux.Unlock()
-		if gotError {
+		if gotError && !ctx.CompileKeepGoing {
 			break
 		}
 		queue <- source
@@ -151,16 +224,22 @@ func compileFiles(ctx *types.Context, sourcePath *paths.Path, recurse bool, buil
 	}
 	close(queue)
 	wg.Wait()
+	objectFiles.Sort()
 	if len(errorsList) > 0 {
-		// output the first error
-		return nil, errors.WithStack(errorsList[0])
+		// output the first error, together with the object files compiled
+		return objectFiles, errors.WithStack(errorsList[0])
 	}
-	objectFiles.Sort()
 	return objectFiles, nil
 }
 
-func compileFileWithRecipe(ctx *types.Context, sourcePath *paths.Path, source *paths.Path, buildPath *paths.Path, buildProperties *properties.Map, includes []string, recipe string) (*paths.Path, error) {
+func compileFileWithRecipe(ctx *types.Context, sourcePath *paths.Path, source *paths.Path, buildPath *paths.Path, buildProperties *properties.Map, includes []string, recipe string, cache *ObjectCache, fileFlags *FileFlags) (*paths.Path, error) {
 	properties := buildProperties.Clone()
+	if flags := fileFlags.Get(source); flags != "" {
+		// the flags come after the global ones, so they take precedence
+		for _, key := range []string{"compiler.c.extra_flags", "compiler.cpp.extra_flags", "compiler.S.extra_flags"} {
+			properties.Set(key, strings.TrimSpace(properties.Get(key)+" "+flags))
+		}
+	}
 	properties.Set(constants.BUILD_PROPERTIES_COMPILER_WARNING_FLAGS, properties.Get(constants.BUILD_PROPERTIES_COMPILER_WARNING_FLAGS+"."+ctx.WarningsLevel))
 	properties.Set(constants.BUILD_PROPERTIES_INCLUDES, strings.Join(includes, constants.SPACE))
 	properties.SetPath("source_file", source)
@@ -187,11 +266,37 @@ func compileFileWithRecipe(ctx *types.Context, sourcePath *paths.Path, source *p
 		return nil, errors.WithStack(err)
 	}
 	if ctx.CompilationDatabase != nil {
-		ctx.CompilationDatabase.Add(source, command)
+		// The command may be run from the build path (see PrepareCommandForRecipe),
+		// so the entry must refer to the source file with an absolute path to be
+		// resolved correctly by clangd.
+		absSource, err := source.Abs()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ctx.CompilationDatabase.Add(absSource, command)
+	}
+	var cacheKey string
+	if cache != nil && !objIsUpToDate && !ctx.OnlyUpdateCompilationDatabase {
+		if key, err := cache.key(ctx, source, objectFile, properties, command); err != nil {
+			logrus.WithError(err).Debugf("Object file %s can't be cached", objectFile)
+		} else if cache.restore(key, objectFile, depsFile) {
+			if ctx.Verbose {
+				ctx.Info(tr("Using cached object file: %[1]s", objectFile))
+			}
+			return objectFile, nil
+		} else {
+			cacheKey = key
+		}
 	}
 	if !objIsUpToDate && !ctx.OnlyUpdateCompilationDatabase {
 		// Since this compile could be multithreaded, we first capture the command output
+		start := time.Now()
 		stdout, stderr, err := utils.ExecCommand(ctx, command, utils.Capture, utils.Capture)
+		elapsed := time.Since(start)
+		logrus.Debugf("Compiled %s in %s", source, elapsed)
+		if ctx.CompileTimes != nil {
+			ctx.CompileTimes.Add(source, elapsed)
+		}
 		// and transfer all at once at the end...
 		if ctx.Verbose {
 			ctx.WriteStdout(stdout)
@@ -202,6 +307,12 @@ func compileFileWithRecipe(ctx *types.Context, sourcePath *paths.Path, source *p
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
+
+		if cacheKey != "" {
+			if err := cache.store(cacheKey, objectFile, depsFile); err != nil {
+				logrus.WithError(err).Debugf("Could not cache object file %s", objectFile)
+			}
+		}
 	} else if ctx.Verbose {
 		if objIsUpToDate {
 			ctx.Info(tr("Using previously compiled file: %[1]s", objectFile))
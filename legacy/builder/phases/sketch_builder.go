@@ -16,39 +16,211 @@
 package phases
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	"github.com/arduino/arduino-cli/legacy/builder/builder_utils"
 	"github.com/arduino/arduino-cli/legacy/builder/types"
 	"github.com/arduino/arduino-cli/legacy/builder/utils"
+	"github.com/arduino/go-paths-helper"
+	"github.com/arduino/go-properties-orderedmap"
 	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
 )
 
 type SketchBuilder struct{}
 
 func (s *SketchBuilder) Run(ctx *types.Context) error {
 	sketchBuildPath := ctx.SketchBuildPath
-	buildProperties := ctx.BuildProperties
-	includes := utils.Map(ctx.IncludeFolders.AsStrings(), utils.WrapWithHyphenI)
+	buildProperties, err := sketchBuildProperties(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	includes := sketchIncludeFlags(ctx)
+	if err := checkSketchExtraObjectFiles(ctx.SketchExtraObjectFiles); err != nil {
+		return errors.WithStack(err)
+	}
 
 	if err := sketchBuildPath.MkdirAll(); err != nil {
 		return errors.WithStack(err)
 	}
-
-	objectFiles, err := builder_utils.CompileFiles(ctx, sketchBuildPath, sketchBuildPath, buildProperties, includes)
-	if err != nil {
+	objectPath := sketchObjectPath(ctx)
+	if err := objectPath.MkdirAll(); err != nil {
 		return errors.WithStack(err)
 	}
 
-	// The "src/" subdirectory of a sketch is compiled recursively
-	sketchSrcPath := sketchBuildPath.Join("src")
-	if sketchSrcPath.IsDir() {
-		srcObjectFiles, err := builder_utils.CompileFilesRecursive(ctx, sketchSrcPath, sketchSrcPath, buildProperties, includes)
+	if ctx.SketchBuilderDryRun {
+		objectFiles, err := predictSketchObjectFiles(sketchBuildPath, sketchSrcFolder(ctx), objectPath)
 		if err != nil {
 			return errors.WithStack(err)
 		}
+		objectFiles.AddAllMissing(ctx.SketchExtraObjectFiles)
+		ctx.SketchObjectFiles = objectFiles
+		return nil
+	}
+
+	var cache *builder_utils.ObjectCache
+	if ctx.SketchObjectCachePath != nil {
+		cache = builder_utils.NewObjectCache(ctx.SketchObjectCachePath, ctx.BuildPath)
+	}
+
+	fileFlagsPatterns := ctx.SketchFileFlags
+	if useSketchPrecompiledHeader(ctx) {
+		if flags := sketchPrecompiledHeader(ctx, buildProperties, includes); flags != "" {
+			fileFlagsPatterns = map[string]string{}
+			for pattern, patternFlags := range ctx.SketchFileFlags {
+				fileFlagsPatterns[pattern] = patternFlags
+			}
+			fileFlagsPatterns[sketchPrecompiledHeaderFiles] = strings.TrimSpace(flags + " " + fileFlagsPatterns[sketchPrecompiledHeaderFiles])
+		}
+	}
+	var fileFlags *builder_utils.FileFlags
+	if len(fileFlagsPatterns) > 0 {
+		fileFlags = builder_utils.NewFileFlags(sketchBuildPath, fileFlagsPatterns)
+	}
+
+	// If the compile fails ctx.SketchObjectFiles are the object files that
+	// have been compiled successfully
+	objectFiles, err := builder_utils.CompileFilesWithCache(ctx, sketchBuildPath, false, objectPath, buildProperties, includes, cache, fileFlags)
+	if err != nil && !ctx.CompileKeepGoing {
+		ctx.SketchObjectFiles = objectFiles
+		return errors.WithStack(err)
+	}
+
+	// The "src/" subdirectory of a sketch is compiled recursively
+	if sketchSrcPath := sketchSrcFolder(ctx); sketchSrcPath != nil {
+		srcObjectFiles, srcErr := builder_utils.CompileFilesWithCache(ctx, sketchSrcPath, true, objectPath.Join("src"), buildProperties, includes, cache, fileFlags)
 		objectFiles.AddAll(srcObjectFiles)
+		if err == nil {
+			err = srcErr
+		}
 	}
 
+	objectFiles.AddAllMissing(ctx.SketchExtraObjectFiles)
 	ctx.SketchObjectFiles = objectFiles
+	return errors.WithStack(err)
+}
 
+// checkSketchExtraObjectFiles returns an error if one of the
+// ctx.SketchExtraObjectFiles is missing or is not a regular file.
+func checkSketchExtraObjectFiles(objectFiles paths.PathList) error {
+	for _, objectFile := range objectFiles {
+		if exist, err := objectFile.ExistCheck(); err != nil {
+			return err
+		} else if !exist || objectFile.IsDir() {
+			return fmt.Errorf(tr("extra object file %s not found", objectFile))
+		}
+	}
 	return nil
 }
+
+// CppStandards are the values allowed for types.Context.SketchCppStandard
+var CppStandards = []string{
+	"c++98", "c++03", "c++11", "c++14", "c++17", "c++20", "c++23",
+	"gnu++98", "gnu++03", "gnu++11", "gnu++14", "gnu++17", "gnu++20", "gnu++23",
+}
+
+// sketchCompilerSourceTypes are the keys allowed in
+// types.Context.SketchCompilers
+var sketchCompilerSourceTypes = []string{"c", "cpp", "S"}
+
+// recipeCommand matches the command at the start of a recipe, quoted or not
+var recipeCommand = regexp.MustCompile(`^\s*("[^"]*"|\S+)`)
+
+// sketchBuildProperties returns the build properties used to compile the
+// sketch: the ctx.BuildProperties with the -std flag for the
+// ctx.SketchCppStandard, if set, added to the C++ extra flags, and the
+// compile recipes using the ctx.SketchCompilers, if any.
+func sketchBuildProperties(ctx *types.Context) (*properties.Map, error) {
+	if ctx.SketchCppStandard == "" && len(ctx.SketchCompilers) == 0 {
+		return ctx.BuildProperties, nil
+	}
+	buildProperties := ctx.BuildProperties.Clone()
+	if ctx.SketchCppStandard != "" {
+		if !slices.Contains(CppStandards, ctx.SketchCppStandard) {
+			return nil, fmt.Errorf(tr("invalid C++ standard %[1]s, allowed values are: %[2]s", ctx.SketchCppStandard, strings.Join(CppStandards, ", ")))
+		}
+		flags := buildProperties.Get("compiler.cpp.extra_flags") + " -std=" + ctx.SketchCppStandard
+		buildProperties.Set("compiler.cpp.extra_flags", strings.TrimSpace(flags))
+	}
+	for sourceType := range ctx.SketchCompilers {
+		if !slices.Contains(sketchCompilerSourceTypes, sourceType) {
+			return nil, fmt.Errorf(tr("invalid source type %[1]s for the sketch compiler, allowed values are: %[2]s", sourceType, strings.Join(sketchCompilerSourceTypes, ", ")))
+		}
+	}
+	for _, sourceType := range sketchCompilerSourceTypes {
+		compiler, ok := ctx.SketchCompilers[sourceType]
+		if !ok {
+			continue
+		}
+		if !compiler.IsNotDir() {
+			return nil, fmt.Errorf(tr("compiler %s not found", compiler))
+		}
+		recipe := "recipe." + sourceType + ".o.pattern"
+		if pattern := buildProperties.Get(recipe); pattern != "" {
+			buildProperties.Set(recipe, recipeCommand.ReplaceAllLiteralString(pattern, `"`+compiler.String()+`"`))
+		}
+	}
+	return buildProperties, nil
+}
+
+// sketchIncludeFlags returns the -I flags used to compile the sketch: the
+// ctx.PriorityIncludeFolders come first, in the given order, followed by the
+// ctx.IncludeFolders. The folders are passed through
+// ctx.SketchIncludeFoldersTransform, if set, and each folder is kept only the
+// first time it appears, so that the same path never gets more than one -I.
+func sketchIncludeFlags(ctx *types.Context) []string {
+	includeFolders := paths.PathList{}
+	includeFolders.AddAllMissing(ctx.PriorityIncludeFolders)
+	includeFolders.AddAllMissing(ctx.IncludeFolders)
+	if ctx.SketchIncludeFoldersTransform != nil {
+		transformed := ctx.SketchIncludeFoldersTransform(includeFolders)
+		includeFolders = paths.PathList{}
+		includeFolders.AddAllMissing(transformed)
+	}
+	return utils.Map(includeFolders.AsStrings(), utils.WrapWithHyphenI)
+}
+
+// sketchSrcFolder returns the "src/" subfolder of the sketch build path, that
+// is compiled recursively, or nil if it doesn't exist or if it must be skipped
+// as requested with ctx.SketchSkipSrcFolder.
+func sketchSrcFolder(ctx *types.Context) *paths.Path {
+	if ctx.SketchSkipSrcFolder {
+		return nil
+	}
+	sketchSrcPath := ctx.SketchBuildPath.Join("src")
+	if !sketchSrcPath.IsDir() {
+		return nil
+	}
+	return sketchSrcPath
+}
+
+// sketchObjectPath returns the folder where the object files of the sketch
+// are written: the ctx.SketchObjectPath, if set, or the sketch build path.
+func sketchObjectPath(ctx *types.Context) *paths.Path {
+	if ctx.SketchObjectPath != nil {
+		return ctx.SketchObjectPath
+	}
+	return ctx.SketchBuildPath
+}
+
+// predictSketchObjectFiles returns the object files that would be produced
+// by the compilation of the sketch, following the same rules of Run. The
+// sketchSrcPath, if not nil, is the folder compiled recursively. The object
+// files are placed in objectPath.
+func predictSketchObjectFiles(sketchBuildPath, sketchSrcPath, objectPath *paths.Path) (paths.PathList, error) {
+	objectFiles, err := builder_utils.PredictObjectFiles(sketchBuildPath, false, objectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if sketchSrcPath != nil {
+		srcObjectFiles, err := builder_utils.PredictObjectFiles(sketchSrcPath, true, objectPath.Join("src"))
+		if err != nil {
+			return nil, err
+		}
+		objectFiles.AddAll(srcObjectFiles)
+	}
+	return objectFiles, nil
+}
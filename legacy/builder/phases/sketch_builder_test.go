@@ -0,0 +1,758 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2023 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package phases
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/arduino/arduino-cli/arduino/builder"
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/arduino-cli/legacy/builder/types"
+	"github.com/arduino/go-paths-helper"
+	"github.com/arduino/go-properties-orderedmap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSketchIncludeFlags(t *testing.T) {
+	ctx := &types.Context{
+		IncludeFolders: paths.NewPathList("sketch", "core", "lib"),
+	}
+	require.Equal(t, []string{"\"-Isketch\"", "\"-Icore\"", "\"-Ilib\""}, sketchIncludeFlags(ctx))
+
+	ctx.PriorityIncludeFolders = paths.NewPathList("core", "variant")
+	require.Equal(t, []string{"\"-Icore\"", "\"-Ivariant\"", "\"-Isketch\"", "\"-Ilib\""}, sketchIncludeFlags(ctx))
+}
+
+func TestSketchIncludeFlagsDeduplicated(t *testing.T) {
+	ctx := &types.Context{
+		PriorityIncludeFolders: paths.NewPathList("variant", "core", "variant"),
+		IncludeFolders:         paths.NewPathList("sketch", "lib", "core", "lib", "sketch"),
+	}
+	require.Equal(t,
+		[]string{"\"-Ivariant\"", "\"-Icore\"", "\"-Isketch\"", "\"-Ilib\""},
+		sketchIncludeFlags(ctx))
+
+	// Folders made identical by the transform are deduplicated too
+	ctx.SketchIncludeFoldersTransform = func(folders paths.PathList) paths.PathList {
+		res := paths.PathList{}
+		for _, folder := range folders {
+			if folder.String() == "lib" {
+				folder = paths.New("sketch")
+			}
+			res.Add(folder)
+		}
+		return res
+	}
+	require.Equal(t,
+		[]string{"\"-Ivariant\"", "\"-Icore\"", "\"-Isketch\""},
+		sketchIncludeFlags(ctx))
+}
+
+func TestSketchIncludeFlagsTransform(t *testing.T) {
+	ctx := &types.Context{
+		IncludeFolders:         paths.NewPathList("sketch", "core", "lib"),
+		PriorityIncludeFolders: paths.NewPathList("variant"),
+	}
+	ctx.SketchIncludeFoldersTransform = func(folders paths.PathList) paths.PathList {
+		require.Equal(t, paths.NewPathList("variant", "sketch", "core", "lib"), folders)
+		res := paths.PathList{}
+		for _, folder := range folders {
+			if folder.String() != "lib" {
+				res.Add(paths.New("sysroot").JoinPath(folder))
+			}
+		}
+		return res
+	}
+	sysroot := paths.New("sysroot")
+	require.Equal(t, []string{
+		"\"-I" + sysroot.Join("variant").String() + "\"",
+		"\"-I" + sysroot.Join("sketch").String() + "\"",
+		"\"-I" + sysroot.Join("core").String() + "\"",
+	}, sketchIncludeFlags(ctx))
+}
+
+func TestSketchBuilderCompilationDatabase(t *testing.T) {
+	buildPath, err := paths.MkTempDir("", "sketch_builder_test")
+	require.NoError(t, err)
+	defer buildPath.RemoveAll()
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.Join("src", "sub").MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("src", "helper.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("src", "sub", "nested.cpp").WriteFile([]byte{}))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("recipe.cpp.o.pattern", `echo "{source_file}" -o "{object_file}"`)
+
+	runSketchBuilder := func(onlyUpdateCompilationDatabase bool) []builder.CompilationCommand {
+		ctx := &types.Context{
+			SketchBuildPath:               sketchBuildPath,
+			BuildProperties:               buildProperties,
+			CompilationDatabase:           builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+			OnlyUpdateCompilationDatabase: onlyUpdateCompilationDatabase,
+		}
+		require.NoError(t, (&SketchBuilder{}).Run(ctx))
+		require.Len(t, ctx.SketchObjectFiles, 3)
+
+		// Jobs run in parallel so the entries may be added in any order
+		entries := ctx.CompilationDatabase.Contents
+		sort.Slice(entries, func(i, j int) bool { return entries[i].File < entries[j].File })
+		return entries
+	}
+
+	dbOnly := runSketchBuilder(true)
+	require.Len(t, dbOnly, 3)
+	require.Equal(t, sketchBuildPath.Join("sketch.ino.cpp").String(), dbOnly[0].File)
+	require.Equal(t, sketchBuildPath.Join("src", "helper.cpp").String(), dbOnly[1].File)
+	require.Equal(t, sketchBuildPath.Join("src", "sub", "nested.cpp").String(), dbOnly[2].File)
+	require.Equal(t, []string{"echo", dbOnly[2].File, "-o", sketchBuildPath.Join("src", "sub", "nested.cpp.o").String()}, dbOnly[2].Arguments)
+
+	require.Equal(t, runSketchBuilder(false), dbOnly)
+}
+
+func TestSketchBuilderSkipSrcFolder(t *testing.T) {
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.Join("src").MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("src", "helper.cpp").WriteFile([]byte{}))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("recipe.cpp.o.pattern", `echo "{source_file}" -o "{object_file}"`)
+	ctx := &types.Context{
+		SketchBuildPath:     sketchBuildPath,
+		BuildProperties:     buildProperties,
+		CompilationDatabase: builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+		SketchSkipSrcFolder: true,
+	}
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	require.Equal(t, paths.NewPathList(sketchBuildPath.Join("sketch.ino.cpp.o").String()), ctx.SketchObjectFiles)
+	require.Len(t, ctx.CompilationDatabase.Contents, 1)
+	require.Equal(t, sketchBuildPath.Join("sketch.ino.cpp").String(), ctx.CompilationDatabase.Contents[0].File)
+
+	ctx.SketchBuilderDryRun = true
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	require.Equal(t, paths.NewPathList(sketchBuildPath.Join("sketch.ino.cpp.o").String()), ctx.SketchObjectFiles)
+}
+
+func TestSketchBuilderExtraObjectFiles(t *testing.T) {
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	extraObjectFile := buildPath.Join("custom.o")
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("recipe.cpp.o.pattern", `echo "{source_file}" -o "{object_file}"`)
+	ctx := &types.Context{
+		SketchBuildPath:        sketchBuildPath,
+		BuildProperties:        buildProperties,
+		CompilationDatabase:    builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+		SketchExtraObjectFiles: paths.NewPathList(extraObjectFile.String()),
+	}
+
+	// The extra object files must exist
+	err := (&SketchBuilder{}).Run(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), extraObjectFile.String())
+	require.Empty(t, ctx.CompilationDatabase.Contents)
+
+	// They are added after the compiled object files
+	require.NoError(t, extraObjectFile.WriteFile([]byte{}))
+	expected := paths.NewPathList(sketchBuildPath.Join("sketch.ino.cpp.o").String(), extraObjectFile.String())
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	require.Equal(t, expected, ctx.SketchObjectFiles)
+
+	ctx.SketchBuilderDryRun = true
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	require.Equal(t, expected, ctx.SketchObjectFiles)
+}
+
+func TestSketchBuilderReadOnlySketchBuildPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test recipes require a POSIX shell")
+	}
+	sketchPath := paths.New(t.TempDir(), "sketch")
+	require.NoError(t, sketchPath.Join("src").MkdirAll())
+	require.NoError(t, sketchPath.Join("sketch.ino").WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	require.NoError(t, sketchPath.Join("src", "helper.cpp").WriteFile([]byte("int helper() { return 1; }\n")))
+	s, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	_, _, err = builder.NewBuilder(s).PrepareSketchBuildPath(nil, sketchBuildPath)
+	require.NoError(t, err)
+
+	// the prepared sources are made read-only for the compile
+	prepared, err := sketchBuildPath.ReadDirRecursive()
+	require.NoError(t, err)
+	for _, file := range append(prepared, sketchBuildPath) {
+		if file.IsDir() {
+			require.NoError(t, os.Chmod(file.String(), 0555))
+			t.Cleanup(func() { os.Chmod(file.String(), 0755) })
+		} else {
+			require.NoError(t, os.Chmod(file.String(), 0444))
+		}
+	}
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("recipe.cpp.o.pattern", `cp "{source_file}" "{object_file}"`)
+	objectPath := buildPath.Join("objects")
+	ctx := &types.Context{
+		SketchBuildPath:  sketchBuildPath,
+		BuildProperties:  buildProperties,
+		SketchObjectPath: objectPath,
+	}
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	expected := paths.NewPathList(objectPath.Join("sketch.ino.cpp.o").String(), objectPath.Join("src", "helper.cpp.o").String())
+	require.Equal(t, expected, ctx.SketchObjectFiles)
+	for _, objectFile := range expected {
+		require.True(t, objectFile.Exist())
+	}
+
+	// nothing has been added to the sketch build path
+	compiled, err := sketchBuildPath.ReadDirRecursive()
+	require.NoError(t, err)
+	require.Equal(t, prepared, compiled)
+
+	ctx.SketchBuilderDryRun = true
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	require.Equal(t, expected, ctx.SketchObjectFiles)
+}
+
+func TestSketchBuilderPrecompiledHeader(t *testing.T) {
+	if _, err := exec.LookPath("g++"); err != nil {
+		t.Skip("the test requires g++")
+	}
+	buildPath := paths.New(t.TempDir())
+	corePath := buildPath.Join("core")
+	require.NoError(t, corePath.MkdirAll())
+	require.NoError(t, corePath.Join("Arduino.h").WriteFile([]byte("#pragma once\ninline int arduinoValue() { return 1; }\n")))
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.Join("src").MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte("#include <Arduino.h>\nint value() { return arduinoValue(); }\n")))
+	require.NoError(t, sketchBuildPath.Join("src", "helper.cpp").WriteFile([]byte("int helper() { return 1; }\n")))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("compiler.cpp.extra_flags", "-O1")
+	buildProperties.Set("recipe.cpp.o.pattern", `g++ -c -MMD {compiler.cpp.extra_flags} {includes} "{source_file}" -o "{object_file}"`)
+	ctx := &types.Context{
+		BuildPath:               buildPath,
+		SketchBuildPath:         sketchBuildPath,
+		BuildProperties:         buildProperties,
+		IncludeFolders:          paths.NewPathList(corePath.String()),
+		CompilationDatabase:     builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+		SketchPrecompiledHeader: true,
+	}
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	precompiled := buildPath.Join("pch", "sketch_pch.h.gch")
+	require.True(t, precompiled.Exist())
+
+	// only the merged .ino files use the precompiled header
+	commands := map[string][]string{}
+	for _, command := range ctx.CompilationDatabase.Contents {
+		commands[paths.New(command.File).Base()] = command.Arguments
+	}
+	require.Contains(t, commands["sketch.ino.cpp"], "-include")
+	require.Contains(t, commands["sketch.ino.cpp"], buildPath.Join("pch", "sketch_pch.h").String())
+	require.NotContains(t, commands["helper.cpp"], "-include")
+
+	// the precompiled header is reused while up to date
+	info, err := precompiled.Stat()
+	require.NoError(t, err)
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	info2, err := precompiled.Stat()
+	require.NoError(t, err)
+	require.Equal(t, info.ModTime(), info2.ModTime())
+
+	// the precompiled header is compiled again if the flags change
+	commandFile := buildPath.Join("pch", "sketch_pch.h.gch.cmd")
+	commandLine, err := commandFile.ReadFile()
+	require.NoError(t, err)
+	require.Contains(t, string(commandLine), "-O1")
+	buildProperties.Set("compiler.cpp.extra_flags", "-O2")
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	commandLine, err = commandFile.ReadFile()
+	require.NoError(t, err)
+	require.Contains(t, string(commandLine), "-O2")
+	require.NotContains(t, string(commandLine), "-O1")
+	require.True(t, precompiled.Exist())
+}
+
+func TestSketchBuilderPrecompiledHeaderCompilationDatabase(t *testing.T) {
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte("void setup() {}\n")))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("recipe.cpp.o.pattern", `echo {compiler.cpp.extra_flags} "{source_file}" -o "{object_file}"`)
+	runSketchBuilder := func(sk *sketch.Sketch) []string {
+		ctx := &types.Context{
+			Sketch:                        sk,
+			BuildPath:                     buildPath,
+			SketchBuildPath:               sketchBuildPath,
+			BuildProperties:               buildProperties,
+			CompilationDatabase:           builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+			OnlyUpdateCompilationDatabase: true,
+			SketchPrecompiledHeader:       true,
+		}
+		require.NoError(t, (&SketchBuilder{}).Run(ctx))
+		require.Len(t, ctx.CompilationDatabase.Contents, 1)
+		return ctx.CompilationDatabase.Contents[0].Arguments
+	}
+
+	// the header is used as in a real build, without being precompiled
+	arguments := runSketchBuilder(nil)
+	require.Contains(t, arguments, "-include")
+	require.Contains(t, arguments, buildPath.Join("pch", "sketch_pch.h").String())
+	require.True(t, buildPath.Join("pch", "sketch_pch.h").Exist())
+	require.False(t, buildPath.Join("pch", "sketch_pch.h.gch").Exist())
+
+	// the header is the prelude of the sketch, it's not used without it
+	sk := &sketch.Sketch{Project: &sketch.Project{NoPrelude: true}}
+	require.NotContains(t, runSketchBuilder(sk), "-include")
+}
+
+func TestSketchBuilderPrecompiledHeaderUnsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test recipes require a POSIX shell")
+	}
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte("void setup() {}\n")))
+
+	// the compiler fails on headers
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("compiler.cpp.extra_flags", "-O1")
+	buildProperties.Set("recipe.cpp.o.pattern", `sh -c "case $0 in *.h) exit 1;; esac; cp $0 $1" "{source_file}" "{object_file}" {compiler.cpp.extra_flags}`)
+	ctx := &types.Context{
+		BuildPath:               buildPath,
+		SketchBuildPath:         sketchBuildPath,
+		BuildProperties:         buildProperties,
+		CompilationDatabase:     builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+		SketchPrecompiledHeader: true,
+	}
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	require.Len(t, ctx.SketchObjectFiles, 1)
+	require.True(t, ctx.SketchObjectFiles[0].Exist())
+	require.Len(t, ctx.CompilationDatabase.Contents, 1)
+	require.NotContains(t, ctx.CompilationDatabase.Contents[0].Arguments, "-include")
+}
+
+func TestSketchBuilderObjectCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test recipes require a POSIX shell")
+	}
+	cachePath := paths.New(t.TempDir())
+
+	buildProperties := properties.NewMap()
+	buildProperties.Set("recipe.cpp.o.pattern", `sh -c "cp $0 $1 && echo $1: $0 > ${1%.o}.d" "{source_file}" "{object_file}"`)
+	buildProperties.Set("recipe.preproc.macros", `cp "{source_file}" "{preprocessed_file_path}"`)
+
+	runSketchBuilder := func(buildPath *paths.Path) *paths.Path {
+		sketchBuildPath := buildPath.Join("sketch")
+		require.NoError(t, sketchBuildPath.MkdirAll())
+		require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte("void setup() {}\n")))
+		props := buildProperties.Clone()
+		props.SetPath("build.path", buildPath)
+		ctx := &types.Context{
+			BuildPath:             buildPath,
+			SketchBuildPath:       sketchBuildPath,
+			BuildProperties:       props,
+			SketchObjectCachePath: cachePath,
+		}
+		require.NoError(t, (&SketchBuilder{}).Run(ctx))
+		require.Len(t, ctx.SketchObjectFiles, 1)
+		return ctx.SketchObjectFiles[0]
+	}
+
+	// The first build populates the cache
+	firstBuildPath := paths.New(t.TempDir())
+	runSketchBuilder(firstBuildPath)
+	entries, err := cachePath.ReadDir()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	cachedDeps, err := entries[0].Join("object.d").ReadFile()
+	require.NoError(t, err)
+	require.NotContains(t, string(cachedDeps), firstBuildPath.String())
+
+	// A build in another build path takes the object file from the cache
+	require.NoError(t, entries[0].Join("object.o").WriteFile([]byte("cached")))
+	secondBuildPath := paths.New(t.TempDir())
+	objectFile := runSketchBuilder(secondBuildPath)
+	data, err := objectFile.ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, "cached", string(data))
+	deps, err := secondBuildPath.Join("sketch", "sketch.ino.cpp.d").ReadFile()
+	require.NoError(t, err)
+	require.Contains(t, string(deps), secondBuildPath.Join("sketch", "sketch.ino.cpp.o").String())
+	require.NotContains(t, string(deps), firstBuildPath.String())
+}
+
+func TestSketchBuilderObjectCacheSources(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test recipes require a POSIX shell")
+	}
+	cachePath := paths.New(t.TempDir())
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("helper.c").WriteFile([]byte("int helper;\n")))
+	require.NoError(t, sketchBuildPath.Join("debug.cpp").WriteFile([]byte("int debug;\n")))
+
+	// The recipes copy the source, the preprocessor is run through the same
+	// recipe without writing the dependency file
+	compile := `sh -c "cp $0 $1 && case $1 in *.o) echo $1: $0 > ${1%.o}.d;; esac" "{source_file}" "{object_file}" `
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("compiler.c.flags", "-Os")
+	buildProperties.Set("compiler.cpp.flags", "-Os")
+	buildProperties.Set("recipe.c.o.pattern", compile+"{compiler.c.flags}")
+	buildProperties.Set("recipe.cpp.o.pattern", compile+"-g {compiler.cpp.flags}")
+
+	ctx := &types.Context{
+		BuildPath:             buildPath,
+		SketchBuildPath:       sketchBuildPath,
+		BuildProperties:       buildProperties,
+		SketchObjectCachePath: cachePath,
+	}
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+	require.Len(t, ctx.SketchObjectFiles, 2)
+
+	// The C source is cached, the object file with debug info is not
+	entries, err := cachePath.ReadDir()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	cached, err := entries[0].Join("object.o").ReadFile()
+	require.NoError(t, err)
+	require.Equal(t, "int helper;\n", string(cached))
+}
+
+func TestBuildSketchVariants(t *testing.T) {
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.Join("src").MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp.o").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("src", "helper.cpp").WriteFile([]byte{}))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("compiler.cpp.extra_flags", "-DCOMMON")
+	buildProperties.Set("recipe.cpp.o.pattern", `echo {compiler.cpp.extra_flags} "{source_file}" -o "{object_file}"`)
+	ctx := &types.Context{
+		BuildPath:           buildPath,
+		SketchBuildPath:     sketchBuildPath,
+		BuildProperties:     buildProperties,
+		CompilationDatabase: builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+	}
+
+	res, err := BuildSketchVariants(ctx, []SketchVariant{
+		{Name: "a", Defines: []string{"VARIANT_A"}},
+		{Name: "b", Defines: []string{"VARIANT=2", "DEBUG"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	for _, name := range []string{"a", "b"} {
+		variantPath := buildPath.Join("variants", name, "sketch")
+		objectFiles := res[name]
+		require.Len(t, objectFiles, 2)
+		require.True(t, objectFiles.Contains(variantPath.Join("sketch.ino.cpp.o")))
+		require.True(t, objectFiles.Contains(variantPath.Join("src", "helper.cpp.o")))
+		require.FileExists(t, variantPath.Join("src", "helper.cpp").String())
+	}
+
+	for _, entry := range ctx.CompilationDatabase.Contents {
+		if paths.New(entry.File).IsInsideDir(buildPath.Join("variants", "a")) {
+			require.Equal(t, []string{"echo", "-DCOMMON", "-DVARIANT_A"}, entry.Arguments[:3])
+		} else {
+			require.Equal(t, []string{"echo", "-DCOMMON", "-DVARIANT=2", "-DDEBUG"}, entry.Arguments[:4])
+		}
+	}
+	require.Len(t, ctx.CompilationDatabase.Contents, 4)
+
+	// the context is restored
+	require.Equal(t, sketchBuildPath, ctx.SketchBuildPath)
+	require.Equal(t, buildProperties, ctx.BuildProperties)
+
+	_, err = BuildSketchVariants(ctx, []SketchVariant{{Name: "../a"}})
+	require.Error(t, err)
+	_, err = BuildSketchVariants(ctx, []SketchVariant{{Name: "a"}, {Name: "a"}})
+	require.Error(t, err)
+}
+
+func TestBuildSketchVariantsObjectPath(t *testing.T) {
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	objectPath := buildPath.Join("objects")
+	require.NoError(t, sketchBuildPath.MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("old.cpp").WriteFile([]byte{}))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("recipe.cpp.o.pattern", `echo "{source_file}" -o "{object_file}"`)
+	ctx := &types.Context{
+		BuildPath:        buildPath,
+		SketchBuildPath:  sketchBuildPath,
+		SketchObjectPath: objectPath,
+		BuildProperties:  buildProperties,
+	}
+
+	// each variant writes its object files in its own folder
+	variants := []SketchVariant{{Name: "a"}, {Name: "b"}}
+	res, err := BuildSketchVariants(ctx, variants)
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b"} {
+		require.Len(t, res[name], 2)
+		require.True(t, res[name].Contains(objectPath.Join("variants", name, "sketch.ino.cpp.o")))
+	}
+	require.Equal(t, objectPath, ctx.SketchObjectPath)
+
+	// the sources removed from the sketch are removed from the variants
+	staleSource := buildPath.Join("variants", "a", "sketch", "old.cpp")
+	require.FileExists(t, staleSource.String())
+	require.NoError(t, paths.New(staleSource.String()+".o").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("old.cpp").Remove())
+	res, err = BuildSketchVariants(ctx, variants)
+	require.NoError(t, err)
+	require.Len(t, res["a"], 1)
+	require.NoFileExists(t, staleSource.String())
+	require.NoFileExists(t, staleSource.String()+".o")
+	require.FileExists(t, buildPath.Join("variants", "a", "sketch", "sketch.ino.cpp").String())
+}
+
+func TestSketchBuilderCompileTimes(t *testing.T) {
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.Join("src").MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("src", "helper.cpp").WriteFile([]byte{}))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("recipe.cpp.o.pattern", `echo "{source_file}" -o "{object_file}"`)
+	ctx := &types.Context{
+		SketchBuildPath: sketchBuildPath,
+		BuildProperties: buildProperties,
+		CompileTimes:    &types.CompileTimes{},
+	}
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+
+	times := ctx.CompileTimes.AsMap()
+	require.Len(t, times, 2)
+	require.Contains(t, times, sketchBuildPath.Join("sketch.ino.cpp").String())
+	require.Contains(t, times, sketchBuildPath.Join("src", "helper.cpp").String())
+	for _, d := range times {
+		require.Greater(t, d, time.Duration(0))
+	}
+}
+
+func TestSketchBuilderFileFlags(t *testing.T) {
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.Join("src").MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("src", "helper.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("src", "helper.c").WriteFile([]byte{}))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("compiler.cpp.extra_flags", "-Os")
+	buildProperties.Set("recipe.cpp.o.pattern", `echo {compiler.cpp.extra_flags} "{source_file}" -o "{object_file}"`)
+	buildProperties.Set("recipe.c.o.pattern", `echo {compiler.c.extra_flags} "{source_file}" -o "{object_file}"`)
+	ctx := &types.Context{
+		SketchBuildPath:     sketchBuildPath,
+		BuildProperties:     buildProperties,
+		CompilationDatabase: builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+		SketchFileFlags:     map[string]string{"src/*.cpp": "-O0"},
+	}
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+
+	commands := map[string][]string{}
+	for _, command := range ctx.CompilationDatabase.Contents {
+		commands[paths.New(command.File).Base()] = command.Arguments
+	}
+	require.Len(t, commands, 3)
+	require.Equal(t, []string{"echo", "-Os", "-O0"}, commands["helper.cpp"][:3])
+	require.Equal(t, []string{"echo", "-Os"}, commands["sketch.ino.cpp"][:2])
+	require.NotContains(t, commands["sketch.ino.cpp"], "-O0")
+	require.NotContains(t, commands["helper.c"], "-O0")
+}
+
+func TestSketchBuilderCppStandard(t *testing.T) {
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("helper.c").WriteFile([]byte{}))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("compiler.cpp.extra_flags", "-Os")
+	buildProperties.Set("recipe.cpp.o.pattern", `echo -std=gnu++11 {compiler.cpp.extra_flags} "{source_file}" -o "{object_file}"`)
+	buildProperties.Set("recipe.c.o.pattern", `echo {compiler.c.extra_flags} "{source_file}" -o "{object_file}"`)
+	ctx := &types.Context{
+		SketchBuildPath:     sketchBuildPath,
+		BuildProperties:     buildProperties,
+		CompilationDatabase: builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+		SketchCppStandard:   "c++17",
+	}
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+
+	commands := map[string][]string{}
+	for _, command := range ctx.CompilationDatabase.Contents {
+		commands[paths.New(command.File).Base()] = command.Arguments
+	}
+	require.Len(t, commands, 2)
+	// the sketch standard comes after the platform one
+	require.Equal(t, []string{"echo", "-std=gnu++11", "-Os", "-std=c++17"}, commands["sketch.ino.cpp"][:4])
+	require.NotContains(t, commands["helper.c"], "-std=c++17")
+	// the build properties are left untouched
+	require.Equal(t, "-Os", buildProperties.Get("compiler.cpp.extra_flags"))
+
+	ctx.SketchCppStandard = "c++16"
+	require.Error(t, (&SketchBuilder{}).Run(ctx))
+}
+
+func TestSketchBuilderCompilers(t *testing.T) {
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("helper.c").WriteFile([]byte{}))
+	compiler := buildPath.Join("patched-g++")
+	require.NoError(t, compiler.WriteFile([]byte{}))
+
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("compiler.path", "/usr/bin/")
+	buildProperties.Set("recipe.cpp.o.pattern", `"{compiler.path}g++" -c "{source_file}" -o "{object_file}"`)
+	buildProperties.Set("recipe.c.o.pattern", `"{compiler.path}gcc" -c "{source_file}" -o "{object_file}"`)
+	ctx := &types.Context{
+		SketchBuildPath:               sketchBuildPath,
+		BuildProperties:               buildProperties,
+		CompilationDatabase:           builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+		OnlyUpdateCompilationDatabase: true,
+		SketchCompilers:               map[string]*paths.Path{"cpp": compiler},
+	}
+	require.NoError(t, (&SketchBuilder{}).Run(ctx))
+
+	commands := map[string][]string{}
+	for _, command := range ctx.CompilationDatabase.Contents {
+		commands[paths.New(command.File).Base()] = command.Arguments
+	}
+	require.Len(t, commands, 2)
+	require.Equal(t, []string{compiler.String(), "-c"}, commands["sketch.ino.cpp"][:2])
+	require.Equal(t, []string{"/usr/bin/gcc", "-c"}, commands["helper.c"][:2])
+	// the build properties are left untouched
+	require.Equal(t, `"{compiler.path}g++" -c "{source_file}" -o "{object_file}"`, buildProperties.Get("recipe.cpp.o.pattern"))
+
+	ctx.SketchCompilers = map[string]*paths.Path{"cpp": buildPath.Join("missing-g++")}
+	require.Error(t, (&SketchBuilder{}).Run(ctx))
+	ctx.SketchCompilers = map[string]*paths.Path{"ino": compiler}
+	require.Error(t, (&SketchBuilder{}).Run(ctx))
+}
+
+func TestSketchSyntaxChecker(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test recipes require a POSIX shell")
+	}
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.Join("src").MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("sketch.ino.cpp").WriteFile([]byte{}))
+	require.NoError(t, sketchBuildPath.Join("src", "helper.cpp").WriteFile([]byte{}))
+
+	// the recipe reports the flags it has been called with
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("compiler.cpp.extra_flags", "-Os")
+	buildProperties.Set("recipe.cpp.o.pattern", `sh -c "echo $0 $1 $2 >&2" {compiler.cpp.extra_flags} "{source_file}"`)
+	stderr := &bytes.Buffer{}
+	ctx := &types.Context{
+		SketchBuildPath:     sketchBuildPath,
+		BuildProperties:     buildProperties,
+		CompilationDatabase: builder.NewCompilationDatabase(buildPath.Join("compile_commands.json")),
+		Stderr:              stderr,
+	}
+	require.NoError(t, (&SketchSyntaxChecker{}).Run(ctx))
+
+	require.Contains(t, stderr.String(), "-Os -fsyntax-only "+sketchBuildPath.Join("sketch.ino.cpp").String())
+	require.Contains(t, stderr.String(), "-Os -fsyntax-only "+sketchBuildPath.Join("src", "helper.cpp").String())
+	require.Empty(t, ctx.CompilationDatabase.Contents)
+	files, err := buildPath.ReadDirRecursive()
+	require.NoError(t, err)
+	for _, file := range files {
+		require.NotEqual(t, ".o", file.Ext())
+		require.NotEqual(t, ".d", file.Ext())
+	}
+
+	// the compile errors are reported
+	buildProperties.Set("recipe.cpp.o.pattern", `sh -c "echo error >&2 && false"`)
+	require.Error(t, (&SketchSyntaxChecker{}).Run(ctx))
+}
+
+func TestSketchBuilderKeepGoing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test recipes require a POSIX shell")
+	}
+	buildPath := paths.New(t.TempDir())
+	sketchBuildPath := buildPath.Join("sketch")
+	require.NoError(t, sketchBuildPath.Join("src").MkdirAll())
+	require.NoError(t, sketchBuildPath.Join("bad.cpp").WriteFile([]byte("bad")))
+	require.NoError(t, sketchBuildPath.Join("good.cpp").WriteFile([]byte("good")))
+	require.NoError(t, sketchBuildPath.Join("src", "helper.cpp").WriteFile([]byte("good")))
+
+	// the recipe fails on the sources containing "bad"
+	buildProperties := properties.NewMap()
+	buildProperties.SetPath("build.path", buildPath)
+	buildProperties.Set("recipe.cpp.o.pattern", `sh -c "! grep -q bad $0 && cp $0 $1" "{source_file}" "{object_file}"`)
+	ctx := &types.Context{
+		SketchBuildPath:  sketchBuildPath,
+		BuildProperties:  buildProperties,
+		CompileKeepGoing: true,
+	}
+	require.Error(t, (&SketchBuilder{}).Run(ctx))
+	require.Equal(t, paths.NewPathList(
+		sketchBuildPath.Join("good.cpp.o").String(),
+		sketchBuildPath.Join("src", "helper.cpp.o").String(),
+	), ctx.SketchObjectFiles)
+
+	// by default the compile stops at the first error
+	ctx.CompileKeepGoing = false
+	require.NoError(t, sketchBuildPath.Join("good.cpp.o").Remove())
+	require.NoError(t, sketchBuildPath.Join("src", "helper.cpp.o").Remove())
+	require.Error(t, (&SketchBuilder{}).Run(ctx))
+	require.False(t, ctx.SketchObjectFiles.Contains(sketchBuildPath.Join("bad.cpp.o")))
+	require.True(t, sketchBuildPath.Join("src", "helper.cpp.o").NotExist())
+}
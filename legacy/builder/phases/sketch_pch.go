@@ -0,0 +1,134 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package phases
+
+import (
+	"os"
+	"strings"
+
+	"github.com/arduino/arduino-cli/legacy/builder/builder_utils"
+	"github.com/arduino/arduino-cli/legacy/builder/constants"
+	"github.com/arduino/arduino-cli/legacy/builder/types"
+	"github.com/arduino/arduino-cli/legacy/builder/utils"
+	"github.com/arduino/go-paths-helper"
+	"github.com/arduino/go-properties-orderedmap"
+	"github.com/pkg/errors"
+)
+
+// sketchPrecompiledHeaderSource is the header precompiled for the sketch
+// sources, it contains the prelude added by the merge of the .ino files
+const sketchPrecompiledHeaderSource = "#include <Arduino.h>\n"
+
+// sketchPrecompiledHeaderFiles is the pattern, as used in
+// types.Context.SketchFileFlags, of the sketch sources using the precompiled
+// header: the ones produced by the merge of the .ino files, that include
+// Arduino.h anyway.
+const sketchPrecompiledHeaderFiles = "*.ino.cpp"
+
+// useSketchPrecompiledHeader returns true if the sketch must be compiled
+// with the precompiled header: the header is the prelude of the merged
+// sketch, so it's not used if the prelude is disabled by the sketch project
+// file.
+func useSketchPrecompiledHeader(ctx *types.Context) bool {
+	if !ctx.SketchPrecompiledHeader {
+		return false
+	}
+	return ctx.Sketch == nil || ctx.Sketch.Project == nil || !ctx.Sketch.Project.NoPrelude
+}
+
+// sketchPrecompiledHeader precompiles, in the "pch" folder of the build
+// path, the header included by the prelude of the sketch, using the same
+// recipe and flags of the C++ sources. It returns the flags that make the
+// compiler use it, or an empty string if the header can't be precompiled
+// (for example because the toolchain doesn't support precompiled headers):
+// in that case the sketch is compiled without it. If only the compilation
+// database is updated the header is not precompiled, but the same flags are
+// returned to produce the same entries of a real build.
+func sketchPrecompiledHeader(ctx *types.Context, buildProperties *properties.Map, includes []string) string {
+	header, err := compileSketchPrecompiledHeader(ctx, buildProperties, includes)
+	if err != nil {
+		if ctx.Verbose {
+			ctx.Info(tr("Could not precompile the sketch header, compiling without it: %s", err))
+		}
+		return ""
+	}
+	return `-include "` + header.String() + `"`
+}
+
+// compileSketchPrecompiledHeader writes the header to precompile, compiles it
+// if it's not up to date, and returns its path.
+func compileSketchPrecompiledHeader(ctx *types.Context, buildProperties *properties.Map, includes []string) (*paths.Path, error) {
+	pchPath := ctx.BuildPath.Join("pch")
+	if err := pchPath.MkdirAll(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	header := pchPath.Join("sketch_pch.h")
+	// the header is rewritten only if changed to keep the precompiled one
+	// up to date
+	if current, err := header.ReadFile(); err != nil || string(current) != sketchPrecompiledHeaderSource {
+		if err := header.WriteFile([]byte(sketchPrecompiledHeaderSource)); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	precompiled := pchPath.Join("sketch_pch.h.gch")
+	depsFile := pchPath.Join("sketch_pch.h.d")
+	// the precompiled header can be used only with the flags used to compile
+	// it: the command line is saved next to it to compile it again when the
+	// flags change
+	commandFile := pchPath.Join("sketch_pch.h.gch.cmd")
+
+	properties := buildProperties.Clone()
+	properties.Set("compiler.cpp.extra_flags", strings.TrimSpace(properties.Get("compiler.cpp.extra_flags")+" -x c++-header"))
+	properties.Set(constants.BUILD_PROPERTIES_COMPILER_WARNING_FLAGS, properties.Get(constants.BUILD_PROPERTIES_COMPILER_WARNING_FLAGS+"."+ctx.WarningsLevel))
+	properties.Set(constants.BUILD_PROPERTIES_INCLUDES, strings.Join(includes, constants.SPACE))
+	properties.SetPath("source_file", header)
+	properties.SetPath(constants.BUILD_PROPERTIES_OBJECT_FILE, precompiled)
+	command, err := builder_utils.PrepareCommandForRecipe(properties, "recipe.cpp.o.pattern", false, ctx.PackageManager.GetEnvVarsForSpawnedProcess())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if ctx.OnlyUpdateCompilationDatabase {
+		return header, nil
+	}
+
+	commandLine := strings.Join(command.Args, "\n")
+	upToDate, err := builder_utils.ObjFileIsUpToDate(header, precompiled, depsFile)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if current, err := commandFile.ReadFile(); upToDate && err == nil && string(current) == commandLine {
+		return header, nil
+	}
+	if err := commandFile.Remove(); err != nil && !os.IsNotExist(err) {
+		return nil, errors.WithStack(err)
+	}
+
+	stdout, stderr, err := utils.ExecCommand(ctx, command, utils.Capture, utils.Capture)
+	if ctx.Verbose {
+		ctx.WriteStdout(stdout)
+		ctx.WriteStderr(stderr)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !precompiled.Exist() {
+		return nil, errors.New(tr("the compiler didn't produce %s", precompiled))
+	}
+	if err := commandFile.WriteFile([]byte(commandLine)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return header, nil
+}
@@ -0,0 +1,47 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package phases
+
+import (
+	"github.com/arduino/arduino-cli/legacy/builder/builder_utils"
+	"github.com/arduino/arduino-cli/legacy/builder/types"
+	"github.com/pkg/errors"
+)
+
+// SketchSyntaxChecker checks that the sketch sources compile, with the same
+// flags and includes used by SketchBuilder, without producing object files.
+type SketchSyntaxChecker struct{}
+
+func (s *SketchSyntaxChecker) Run(ctx *types.Context) error {
+	sketchBuildPath := ctx.SketchBuildPath
+	buildProperties, err := sketchBuildProperties(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	includes := sketchIncludeFlags(ctx)
+
+	if err := builder_utils.CheckFilesSyntax(ctx, sketchBuildPath, false, buildProperties, includes); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// The "src/" subdirectory of a sketch is checked recursively
+	if sketchSrcPath := sketchSrcFolder(ctx); sketchSrcPath != nil {
+		if err := builder_utils.CheckFilesSyntax(ctx, sketchSrcPath, true, buildProperties, includes); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package phases
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/arduino/arduino-cli/legacy/builder/types"
+	"github.com/arduino/go-paths-helper"
+	"github.com/pkg/errors"
+)
+
+// SketchVariant is a named set of preprocessor defines used to compile a
+// variant of the sketch.
+type SketchVariant struct {
+	Name string
+	// Defines are in the form NAME or NAME=VALUE
+	Defines []string
+}
+
+// BuildSketchVariants compiles the already preprocessed sketch once for each
+// of the given variants, adding the variant defines to the compiler flags.
+// Each variant is compiled in its own build path, inside the "variants"
+// folder of the build path, so that the object files of a variant are reused
+// by the following builds of the same variant. If ctx.SketchObjectPath is set
+// the object files of each variant are written in its "variants/<name>"
+// subfolder instead. The object files of each variant are returned, keyed by
+// variant name.
+// The ctx.SketchBuildPath, ctx.SketchObjectPath, ctx.BuildProperties and
+// ctx.SketchObjectFiles are restored before returning.
+func BuildSketchVariants(ctx *types.Context, variants []SketchVariant) (map[string]paths.PathList, error) {
+	names := map[string]bool{}
+	for _, variant := range variants {
+		if variant.Name == "" || variant.Name == "." || variant.Name == ".." || strings.ContainsAny(variant.Name, `/\`) {
+			return nil, errors.New(tr("invalid sketch variant name: %s", variant.Name))
+		}
+		if names[variant.Name] {
+			return nil, errors.New(tr("duplicate sketch variant: %s", variant.Name))
+		}
+		names[variant.Name] = true
+	}
+
+	sketchBuildPath := ctx.SketchBuildPath
+	sketchObjectPath := ctx.SketchObjectPath
+	buildProperties := ctx.BuildProperties
+	sketchObjectFiles := ctx.SketchObjectFiles
+	defer func() {
+		ctx.SketchBuildPath = sketchBuildPath
+		ctx.SketchObjectPath = sketchObjectPath
+		ctx.BuildProperties = buildProperties
+		ctx.SketchObjectFiles = sketchObjectFiles
+	}()
+
+	res := map[string]paths.PathList{}
+	for _, variant := range variants {
+		variantBuildPath := ctx.BuildPath.Join("variants", variant.Name, "sketch")
+		if err := mirrorSketchSources(sketchBuildPath, variantBuildPath); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		flags := ""
+		for _, define := range variant.Defines {
+			flags += fmt.Sprintf(" \"-D%s\"", define)
+		}
+		variantProperties := buildProperties.Clone()
+		for _, key := range []string{"compiler.c.extra_flags", "compiler.cpp.extra_flags", "compiler.S.extra_flags"} {
+			variantProperties.Set(key, strings.TrimSpace(variantProperties.Get(key)+flags))
+		}
+
+		ctx.SketchBuildPath = variantBuildPath
+		if sketchObjectPath != nil {
+			ctx.SketchObjectPath = sketchObjectPath.Join("variants", variant.Name)
+		}
+		ctx.BuildProperties = variantProperties
+		if err := (&SketchBuilder{}).Run(ctx); err != nil {
+			return nil, errors.WithMessage(err, tr("compiling sketch variant %s", variant.Name))
+		}
+		res[variant.Name] = ctx.SketchObjectFiles
+	}
+	return res, nil
+}
+
+// isBuildProduct returns true if file is produced by the compile of the
+// sketch rather than being one of its sources
+func isBuildProduct(file *paths.Path) bool {
+	switch file.Ext() {
+	case ".o", ".d", ".preproc":
+		return true
+	}
+	return false
+}
+
+// mirrorSketchSources copies the sources in the sketch build path src to dst,
+// skipping the build products. The files already up to date are not
+// rewritten, to keep the object files of dst valid. The files of dst that are
+// not in src anymore are removed, together with their build products.
+func mirrorSketchSources(src, dst *paths.Path) error {
+	files, err := src.ReadDirRecursive()
+	if err != nil {
+		return err
+	}
+	mirrored := map[string]bool{}
+	for _, file := range files {
+		if file.IsDir() || isBuildProduct(file) {
+			continue
+		}
+		rel, err := src.RelTo(file)
+		if err != nil {
+			return err
+		}
+		mirrored[rel.String()] = true
+		target := dst.JoinPath(rel)
+		data, err := file.ReadFile()
+		if err != nil {
+			return err
+		}
+		if current, err := target.ReadFile(); err == nil && bytes.Equal(current, data) {
+			continue
+		}
+		if err := target.Parent().MkdirAll(); err != nil {
+			return err
+		}
+		if err := target.WriteFile(data); err != nil {
+			return err
+		}
+	}
+	return removeStaleMirroredSources(dst, mirrored)
+}
+
+// removeStaleMirroredSources removes the files of dst, apart from the build
+// products, that are not in mirrored (the paths relative to dst of the
+// mirrored sources), together with their object and dependency files.
+func removeStaleMirroredSources(dst *paths.Path, mirrored map[string]bool) error {
+	if dst.NotExist() {
+		return nil
+	}
+	files, err := dst.ReadDirRecursive()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || isBuildProduct(file) {
+			continue
+		}
+		rel, err := dst.RelTo(file)
+		if err != nil {
+			return err
+		}
+		if mirrored[rel.String()] {
+			continue
+		}
+		for _, stale := range []*paths.Path{file, paths.New(file.String() + ".o"), paths.New(file.String() + ".d")} {
+			if err := stale.Remove(); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
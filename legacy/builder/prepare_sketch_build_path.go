@@ -0,0 +1,43 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	"github.com/arduino/arduino-cli/arduino/builder"
+	"github.com/arduino/arduino-cli/legacy/builder/types"
+	"github.com/sirupsen/logrus"
+)
+
+// PrepareSketchBuildPath copies the sketch sources in ctx.SketchBuildPath,
+// merging the .ino files and removing the copies of the files that are not
+// part of the sketch anymore. The sketch is prepared with the same options by
+// every kind of build (Builder, Preprocess and SyntaxCheck), so that they
+// see the same sources and report the same warnings.
+type PrepareSketchBuildPath struct{}
+
+func (s *PrepareSketchBuildPath) Run(ctx *types.Context) error {
+	sketchBuilder := builder.NewBuilder(ctx.Sketch)
+	sketchBuilder.Jobs = ctx.Jobs
+	sketchBuilder.SplitTranslationUnits = ctx.SplitSketchTranslationUnits
+	sketchBuilder.ReportDuplicateFunction = func(err error) { ctx.Warn(tr("Warning: %s", err)) }
+	offset, mergedSource, err := sketchBuilder.PrepareSketchBuildPath(ctx.SourceOverride, ctx.SketchBuildPath)
+	if err != nil {
+		return err
+	}
+	ctx.LineOffset, ctx.SketchSourceMerged = offset, mergedSource
+	logrus.Debug(sketchBuilder.MergeLayout())
+	return nil
+}
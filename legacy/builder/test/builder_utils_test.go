@@ -160,3 +160,32 @@ func TestObjFileIsUpToDateDepIsWrong(t *testing.T) {
 	NoError(t, err)
 	require.False(t, upToDate)
 }
+
+func TestPredictObjectFiles(t *testing.T) {
+	sourcePath, err := paths.MkTempDir("", "predict_object_files")
+	NoError(t, err)
+	defer sourcePath.RemoveAll()
+
+	NoError(t, sourcePath.Join("sub").MkdirAll())
+	NoError(t, sourcePath.Join("a.cpp").WriteFile([]byte{}))
+	NoError(t, sourcePath.Join("b.c").WriteFile([]byte{}))
+	NoError(t, sourcePath.Join("readme.txt").WriteFile([]byte{}))
+	NoError(t, sourcePath.Join("sub", "c.S").WriteFile([]byte{}))
+
+	buildPath := paths.New("build")
+
+	objectFiles, err := builder_utils.PredictObjectFiles(sourcePath, false, buildPath)
+	NoError(t, err)
+	require.Equal(t, paths.PathList{
+		buildPath.Join("a.cpp.o"),
+		buildPath.Join("b.c.o"),
+	}, objectFiles)
+
+	objectFiles, err = builder_utils.PredictObjectFiles(sourcePath, true, buildPath)
+	NoError(t, err)
+	require.Equal(t, paths.PathList{
+		buildPath.Join("a.cpp.o"),
+		buildPath.Join("b.c.o"),
+		buildPath.Join("sub", "c.S.o"),
+	}, objectFiles)
+}
@@ -0,0 +1,85 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/arduino/arduino-cli/arduino/sketch"
+	"github.com/arduino/arduino-cli/legacy/builder"
+	"github.com/arduino/arduino-cli/legacy/builder/types"
+	paths "github.com/arduino/go-paths-helper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareSketchBuildPathReportsDuplicateFunctions(t *testing.T) {
+	sketchPath := paths.New(t.TempDir()).Join("Dup")
+	require.NoError(t, sketchPath.MkdirAll())
+	require.NoError(t, sketchPath.Join("Dup.ino").WriteFile([]byte("void setup() {}\nvoid loop() {}\nvoid helper() {}\n")))
+	require.NoError(t, sketchPath.Join("other.ino").WriteFile([]byte("void helper() {}\n")))
+	sk, err := sketch.New(sketchPath)
+	require.NoError(t, err)
+
+	stderr := &bytes.Buffer{}
+	ctx := &types.Context{
+		Sketch:          sk,
+		SketchBuildPath: paths.New(t.TempDir()),
+		Stderr:          stderr,
+	}
+	require.NoError(t, (&builder.PrepareSketchBuildPath{}).Run(ctx))
+	require.Contains(t, stderr.String(), "function helper is defined both in")
+	require.Contains(t, ctx.SketchSourceMerged, "void setup() {}")
+	require.True(t, ctx.SketchBuildPath.Join("Dup.ino.cpp").Exist())
+}
+
+func TestPrepareSketchBuildPathRemovesStaleFiles(t *testing.T) {
+	sketchPath := paths.New(t.TempDir()).Join("Stale")
+	require.NoError(t, sketchPath.Join("src").MkdirAll())
+	require.NoError(t, sketchPath.Join("Stale.ino").WriteFile([]byte("void setup() {}\nvoid loop() {}\n")))
+	require.NoError(t, sketchPath.Join("src", "helper.cpp").WriteFile([]byte("int helper() { return 0; }\n")))
+
+	ctx := &types.Context{
+		BuildPath: paths.New(t.TempDir()),
+		Stderr:    &bytes.Buffer{},
+	}
+	prepare := func() {
+		sk, err := sketch.New(sketchPath)
+		require.NoError(t, err)
+		ctx.Sketch = sk
+		commands := []types.Command{
+			&builder.AddAdditionalEntriesToContext{},
+			&builder.PrepareSketchBuildPath{},
+		}
+		for _, command := range commands {
+			require.NoError(t, command.Run(ctx))
+		}
+	}
+	prepare()
+	helper := ctx.SketchBuildPath.Join("src", "helper.cpp")
+	require.True(t, helper.Exist())
+	// simulate a previous compilation
+	require.NoError(t, paths.New(helper.String()+".o").WriteFile([]byte{}))
+	require.NoError(t, paths.New(helper.String()+".d").WriteFile([]byte{}))
+
+	// the copy of a file removed from the sketch must not be compiled anymore
+	require.NoError(t, sketchPath.Join("src", "helper.cpp").Remove())
+	prepare()
+	require.False(t, helper.Exist())
+	require.False(t, paths.New(helper.String()+".o").Exist())
+	require.False(t, paths.New(helper.String()+".d").Exist())
+	require.True(t, ctx.SketchBuildPath.Join("Stale.ino.cpp").Exist())
+}
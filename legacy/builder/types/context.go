@@ -90,6 +90,7 @@ type Context struct {
 	SketchBuildPath              *paths.Path
 	CoreBuildPath                *paths.Path
 	CoreBuildCachePath           *paths.Path
+	SketchObjectCachePath        *paths.Path
 	CoreArchiveFilePath          *paths.Path
 	CoreObjectsFiles             paths.PathList
 	LibrariesBuildPath           *paths.Path
@@ -118,6 +119,9 @@ type Context struct {
 	LibrariesResolutionResults   map[string]LibraryResolutionResult
 	IncludeFolders               paths.PathList
 	UseCachedLibrariesResolution bool
+	// Include folders that always take precedence over IncludeFolders
+	// when compiling the sketch
+	PriorityIncludeFolders paths.PathList
 
 	// C++ Parsing
 	LineOffset                  int
@@ -158,6 +162,61 @@ type Context struct {
 	CompilationDatabase *builder.CompilationDatabase
 	// Set to true to skip build and produce only Compilation Database
 	OnlyUpdateCompilationDatabase bool
+	// Set to true to only predict the sketch object files without compiling them
+	SketchBuilderDryRun bool
+	// Set to true to compile only the top-level sketch sources, leaving out
+	// the "src/" subfolder that is otherwise compiled recursively
+	SketchSkipSrcFolder bool
+	// Set to true to compile each .ino file of the sketch as a separate
+	// translation unit, see builder.Builder.SplitTranslationUnits
+	SplitSketchTranslationUnits bool
+	// Set to true to compile all the source files even if some of them fail,
+	// to report all the compile errors at once
+	CompileKeepGoing bool
+	// If set, the time taken to compile each source file is recorded here
+	CompileTimes *CompileTimes
+	// Extra compiler flags for some of the sketch source files, keyed by a
+	// glob pattern (see path.Match) matched against the path of the source
+	// relative to the sketch build path (for example "src/*.cpp", the merged
+	// .ino files are compiled as "<sketch>.ino.cpp"). The flags are appended
+	// to the compiler.{c,cpp,S}.extra_flags, so they come after the global
+	// flags and take precedence over them (for example a -O0 overrides -Os).
+	SketchFileFlags map[string]string
+	// If set, it's called with the include folders of the sketch, before they
+	// are turned into -I flags, and the returned folders are used instead.
+	// It may be used to rewrite, dedupe or reorder the include folders.
+	SketchIncludeFoldersTransform func(paths.PathList) paths.PathList
+	// If set, the C++ sources of the sketch are compiled with this language
+	// standard (for example "c++17" or "gnu++17", see phases.CppStandards).
+	// The -std flag is appended to compiler.cpp.extra_flags, that usually
+	// follows the platform flags in the recipe: in that case it overrides the
+	// -std flag of the platform, since the compiler uses the last one given.
+	// The core and the libraries are not affected, to change the standard
+	// for all the sources set compiler.cpp.extra_flags in the build
+	// properties instead.
+	SketchCppStandard string
+	// Compilers used instead of the platform ones for the sketch sources,
+	// keyed by source type ("c", "cpp" or "S"): the command at the start of
+	// the recipe.<type>.o.pattern is replaced with the given executable, the
+	// arguments are kept. The core and the libraries are still compiled with
+	// the compilers of the platform.
+	SketchCompilers map[string]*paths.Path
+	// Object files built outside of arduino-cli that are added, after the
+	// compiled ones, to the SketchObjectFiles so that they are linked with
+	// the sketch. They must exist when the sketch is built.
+	SketchExtraObjectFiles paths.PathList
+	// If set, the object files of the sketch are written in this folder
+	// instead of the SketchBuildPath. Since the sketch preparation writes all
+	// the sketch sources in the SketchBuildPath before the compile, this
+	// allows to compile with the SketchBuildPath made read-only.
+	SketchObjectPath *paths.Path
+	// Set to true to precompile the Arduino.h header, included by the merged
+	// .ino files, and to use the precompiled header to compile them. The
+	// header is compiled in the "pch" folder of the build path with the C++
+	// recipe of the platform. If the toolchain can't precompile it the
+	// sketch is compiled without it. It's ignored if the sketch disables the
+	// prelude (see sketch.Project.NoPrelude).
+	SketchPrecompiledHeader bool
 
 	// Source code overrides (filename -> content map).
 	// The provided source data is used instead of reading it from disk.
@@ -18,6 +18,8 @@ package types
 import (
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/arduino/arduino-cli/arduino/libraries"
 	"github.com/arduino/arduino-cli/arduino/sketch"
@@ -128,3 +130,31 @@ type BareCommand func(ctx *Context) error
 func (cmd BareCommand) Run(ctx *Context) error {
 	return cmd(ctx)
 }
+
+// CompileTimes records the time taken to compile each source file, keyed by
+// source file path. It's safe for concurrent use.
+type CompileTimes struct {
+	lock  sync.Mutex
+	times map[string]time.Duration
+}
+
+// Add records that source took d to compile
+func (c *CompileTimes) Add(source *paths.Path, d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.times == nil {
+		c.times = map[string]time.Duration{}
+	}
+	c.times[source.String()] = d
+}
+
+// AsMap returns a copy of the recorded compile times
+func (c *CompileTimes) AsMap() map[string]time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	res := map[string]time.Duration{}
+	for source, d := range c.times {
+		res[source] = d
+	}
+	return res
+}
@@ -0,0 +1,38 @@
+// This file is part of arduino-cli.
+//
+// Copyright 2020 ARDUINO SA (http://www.arduino.cc/)
+//
+// This software is released under the GNU General Public License version 3,
+// which covers the main part of arduino-cli.
+// The terms of this license can be found at:
+// https://www.gnu.org/licenses/gpl-3.0.en.html
+//
+// You can be released from the requirements of the above licenses by purchasing
+// a commercial license. Buying such a license is mandatory if you want to
+// modify or otherwise use the software for commercial activities involving the
+// Arduino software without disclosing the source code of your own applications.
+// To purchase a commercial license, send an email to license@arduino.cc.
+
+package builder
+
+import (
+	bldr "github.com/arduino/arduino-cli/arduino/builder"
+	"github.com/arduino/arduino-cli/legacy/builder/types"
+)
+
+// WarnAboutMissingIncludeGuards warns about the sketch headers without an
+// include guard, when the "more" or "all" compiler warnings are enabled.
+// The warnings are advisory and never fail the build.
+type WarnAboutMissingIncludeGuards struct{}
+
+func (s *WarnAboutMissingIncludeGuards) Run(ctx *types.Context) error {
+	if ctx.WarningsLevel != "more" && ctx.WarningsLevel != "all" {
+		return nil
+	}
+	for _, header := range bldr.HeadersWithoutIncludeGuard(ctx.Sketch, ctx.SourceOverride) {
+		ctx.Info(
+			tr("WARNING: header %[1]s has no include guard or #pragma once, including it more than once may cause redefinition errors.",
+				header))
+	}
+	return nil
+}
@@ -25,6 +25,8 @@ type DebugServiceClient interface {
 	// Start a debug session and communicate with the debugger tool.
 	Debug(ctx context.Context, opts ...grpc.CallOption) (DebugService_DebugClient, error)
 	GetDebugConfig(ctx context.Context, in *DebugConfigRequest, opts ...grpc.CallOption) (*GetDebugConfigResponse, error)
+	// List the installed boards and report which of them support debugging.
+	ListDebuggableBoards(ctx context.Context, in *ListDebuggableBoardsRequest, opts ...grpc.CallOption) (*ListDebuggableBoardsResponse, error)
 }
 
 type debugServiceClient struct {
@@ -75,6 +77,15 @@ func (c *debugServiceClient) GetDebugConfig(ctx context.Context, in *DebugConfig
 	return out, nil
 }
 
+func (c *debugServiceClient) ListDebuggableBoards(ctx context.Context, in *ListDebuggableBoardsRequest, opts ...grpc.CallOption) (*ListDebuggableBoardsResponse, error) {
+	out := new(ListDebuggableBoardsResponse)
+	err := c.cc.Invoke(ctx, "/cc.arduino.cli.debug.v1.DebugService/ListDebuggableBoards", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DebugServiceServer is the server API for DebugService service.
 // All implementations must embed UnimplementedDebugServiceServer
 // for forward compatibility
@@ -82,6 +93,8 @@ type DebugServiceServer interface {
 	// Start a debug session and communicate with the debugger tool.
 	Debug(DebugService_DebugServer) error
 	GetDebugConfig(context.Context, *DebugConfigRequest) (*GetDebugConfigResponse, error)
+	// List the installed boards and report which of them support debugging.
+	ListDebuggableBoards(context.Context, *ListDebuggableBoardsRequest) (*ListDebuggableBoardsResponse, error)
 	mustEmbedUnimplementedDebugServiceServer()
 }
 
@@ -95,6 +108,9 @@ func (UnimplementedDebugServiceServer) Debug(DebugService_DebugServer) error {
 func (UnimplementedDebugServiceServer) GetDebugConfig(context.Context, *DebugConfigRequest) (*GetDebugConfigResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetDebugConfig not implemented")
 }
+func (UnimplementedDebugServiceServer) ListDebuggableBoards(context.Context, *ListDebuggableBoardsRequest) (*ListDebuggableBoardsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDebuggableBoards not implemented")
+}
 func (UnimplementedDebugServiceServer) mustEmbedUnimplementedDebugServiceServer() {}
 
 // UnsafeDebugServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -152,6 +168,24 @@ func _DebugService_GetDebugConfig_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DebugService_ListDebuggableBoards_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDebuggableBoardsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DebugServiceServer).ListDebuggableBoards(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/cc.arduino.cli.debug.v1.DebugService/ListDebuggableBoards",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DebugServiceServer).ListDebuggableBoards(ctx, req.(*ListDebuggableBoardsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DebugService_ServiceDesc is the grpc.ServiceDesc for DebugService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -163,6 +197,10 @@ var DebugService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetDebugConfig",
 			Handler:    _DebugService_GetDebugConfig_Handler,
 		},
+		{
+			MethodName: "ListDebuggableBoards",
+			Handler:    _DebugService_ListDebuggableBoards_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{